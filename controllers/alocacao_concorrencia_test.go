@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// requisicoesConcorrentes é a quantidade de alocações enviadas ao mesmo tempo para a mesma sala e horário
+const requisicoesConcorrentes = 8
+
+// TestCreateAlocacaoConcorrente envia várias alocações simultâneas para a mesma sala, dia e horário,
+// cada uma com professor e turma próprios, e verifica que apenas uma é gravada. Precisa de um banco
+// PostgreSQL informado em DATABASE_URL; sem ele, o teste é ignorado.
+func TestCreateAlocacaoConcorrente(t *testing.T) {
+	url := os.Getenv("DATABASE_URL")
+	if url == "" {
+		t.Skip("DATABASE_URL não definida")
+	}
+	t.Setenv("RESEND_API_KEY", "")
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("erro ao conectar ao banco de dados: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("erro ao conectar ao banco de dados: %v", err)
+	}
+	models.MigrateTables(db)
+
+	r := mux.NewRouter()
+	SetupRoutes(r, db)
+
+	var periodoID, salaID int
+	var professorIDs, turmaIDs []int
+
+	// Remover os registros criados pelo teste, começando pelas alocações que referenciam os demais.
+	// A limpeza é registrada antes da criação para rodar mesmo se o teste parar no meio.
+	t.Cleanup(func() {
+		remover := func(consulta string, args ...any) {
+			if _, err := db.Exec(consulta, args...); err != nil {
+				t.Errorf("erro ao remover os dados do teste (%s): %v", consulta, err)
+			}
+		}
+		remover("DELETE FROM alocacoes WHERE sala_id = $1", salaID)
+		remover("DELETE FROM turmas WHERE id = ANY($1)", pq.Array(turmaIDs))
+		remover("DELETE FROM professores WHERE id = ANY($1)", pq.Array(professorIDs))
+		remover("DELETE FROM salas WHERE id = $1", salaID)
+		remover("DELETE FROM periodos_letivos WHERE id = $1", periodoID)
+	})
+
+	// Os registros do teste usam um sufixo próprio e um período letivo distante para não colidir com os dados do banco
+	sufixo := time.Now().UnixNano()
+	periodoID = criarRecurso(t, r, "/api/periodos-letivos", map[string]any{
+		"nome":        fmt.Sprintf("Teste concorrência %d", sufixo),
+		"data_inicio": "2190-02-01",
+		"data_fim":    "2190-06-30",
+	})
+
+	salaID = criarRecurso(t, r, "/api/salas", map[string]any{
+		"numero":     fmt.Sprintf("T%d", sufixo),
+		"capacidade": 40,
+		"bloco":      "Teste",
+		"tipo":       "Sala comum",
+	})
+
+	corpos := make([][]byte, requisicoesConcorrentes)
+	for i := range corpos {
+		professorID := criarRecurso(t, r, "/api/professores", map[string]any{
+			"nome":       fmt.Sprintf("Professor %d", i),
+			"email":      fmt.Sprintf("professor%d.%d@teste.com", i, sufixo),
+			"disciplina": "Teste",
+		})
+		professorIDs = append(professorIDs, professorID)
+		turmaID := criarRecurso(t, r, "/api/turmas", map[string]any{
+			"nome":         fmt.Sprintf("Turma %d-%d", i, sufixo),
+			"curso":        "Teste",
+			"periodo":      "1",
+			"quant_alunos": 30,
+		})
+		turmaIDs = append(turmaIDs, turmaID)
+
+		corpos[i], _ = json.Marshal(map[string]any{
+			"professor_id":      professorID,
+			"sala_id":           salaID,
+			"turma_id":          turmaID,
+			"periodo_letivo_id": periodoID,
+			"dia_semana":        "Segunda",
+			"horario_inicio":    "19:00",
+			"horario_fim":       "22:30",
+		})
+	}
+
+	// Disparar todas as requisições juntas
+	status := make([]int, requisicoesConcorrentes)
+	respostas := make([]string, requisicoesConcorrentes)
+	inicio := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, corpo := range corpos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-inicio
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/alocacoes", bytes.NewReader(corpo)))
+			status[i], respostas[i] = w.Code, w.Body.String()
+		}()
+	}
+	close(inicio)
+	wg.Wait()
+
+	criadas, conflitos := 0, 0
+	for i, s := range status {
+		switch s {
+		case http.StatusCreated:
+			criadas++
+		case http.StatusConflict:
+			conflitos++
+		default:
+			t.Errorf("requisição %d: status %d inesperado: %s", i, s, respostas[i])
+		}
+	}
+	if criadas != 1 || conflitos != requisicoesConcorrentes-1 {
+		t.Errorf("esperava 1 alocação criada e %d conflitos, obteve %d e %d", requisicoesConcorrentes-1, criadas, conflitos)
+	}
+
+	var gravadas int
+	if err := db.QueryRow("SELECT COUNT(*) FROM alocacoes WHERE sala_id = $1", salaID).Scan(&gravadas); err != nil {
+		t.Fatalf("erro ao contar as alocações: %v", err)
+	}
+	if gravadas != 1 {
+		t.Errorf("esperava 1 alocação gravada na sala, encontrou %d", gravadas)
+	}
+}
+
+// criarRecurso cria um registro pela API e retorna o seu ID
+func criarRecurso(t *testing.T, r *mux.Router, caminho string, dados map[string]any) int {
+	t.Helper()
+
+	corpo, _ := json.Marshal(dados)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, caminho, bytes.NewReader(corpo)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST %s: status %d: %s", caminho, w.Code, w.Body.String())
+	}
+
+	var criado struct {
+		ID int `json:"id"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&criado); err != nil {
+		t.Fatalf("POST %s: resposta inválida: %v", caminho, err)
+	}
+	return criado.ID
+}
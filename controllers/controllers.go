@@ -3,6 +3,7 @@ package controllers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -33,6 +34,67 @@ type AlocacaoController struct {
 	Repo *repositories.AlocacaoRepository
 }
 
+// DisponibilidadeController gerencia as requisições relacionadas à disponibilidade dos professores
+type DisponibilidadeController struct {
+	Repo          *repositories.DisponibilidadeRepository
+	ProfessorRepo *repositories.ProfessorRepository
+}
+
+// BloqueioSalaController gerencia as requisições relacionadas aos bloqueios de salas
+type BloqueioSalaController struct {
+	Repo     *repositories.BloqueioSalaRepository
+	SalaRepo *repositories.SalaRepository
+}
+
+// PreferenciaController gerencia as requisições relacionadas às preferências dos professores
+type PreferenciaController struct {
+	Repo          *repositories.PreferenciaRepository
+	ProfessorRepo *repositories.ProfessorRepository
+}
+
+// TurnoController gerencia as requisições relacionadas aos turnos
+type TurnoController struct {
+	Repo *repositories.TurnoRepository
+}
+
+// DisciplinaController gerencia as requisições relacionadas às disciplinas e aos professores que as lecionam
+type DisciplinaController struct {
+	Repo          *repositories.DisciplinaRepository
+	ProfessorRepo *repositories.ProfessorRepository
+}
+
+// CursoController gerencia as requisições relacionadas aos cursos e às suas grades curriculares
+type CursoController struct {
+	Repo *repositories.CursoRepository
+}
+
+// CampusController gerencia as requisições relacionadas aos campi
+type CampusController struct {
+	Repo      *repositories.CampusRepository
+	BlocoRepo *repositories.BlocoRepository
+}
+
+// BlocoController gerencia as requisições relacionadas aos blocos e aos tempos de deslocamento entre eles
+type BlocoController struct {
+	Repo *repositories.BlocoRepository
+}
+
+// PeriodoLetivoController gerencia as requisições relacionadas aos períodos letivos
+type PeriodoLetivoController struct {
+	Repo *repositories.PeriodoLetivoRepository
+}
+
+// DiaNaoLetivoController gerencia as requisições relacionadas ao calendário de dias não letivos
+type DiaNaoLetivoController struct {
+	Repo *repositories.DiaNaoLetivoRepository
+}
+
+// ExcecaoAlocacaoController gerencia as requisições relacionadas às exceções das alocações em datas específicas
+type ExcecaoAlocacaoController struct {
+	Repo         *repositories.ExcecaoAlocacaoRepository
+	AlocacaoRepo *repositories.AlocacaoRepository
+}
+
 // NewProfessorController cria um novo controlador de professores
 func NewProfessorController(db *sql.DB) *ProfessorController {
 	return &ProfessorController{
@@ -61,6 +123,89 @@ func NewAlocacaoController(db *sql.DB) *AlocacaoController {
 	}
 }
 
+// NewDisponibilidadeController cria um novo controlador de disponibilidade dos professores
+func NewDisponibilidadeController(db *sql.DB) *DisponibilidadeController {
+	return &DisponibilidadeController{
+		Repo:          repositories.NewDisponibilidadeRepository(db),
+		ProfessorRepo: repositories.NewProfessorRepository(db),
+	}
+}
+
+// NewBloqueioSalaController cria um novo controlador de bloqueios de salas
+func NewBloqueioSalaController(db *sql.DB) *BloqueioSalaController {
+	return &BloqueioSalaController{
+		Repo:     repositories.NewBloqueioSalaRepository(db),
+		SalaRepo: repositories.NewSalaRepository(db),
+	}
+}
+
+// NewPreferenciaController cria um novo controlador de preferências dos professores
+func NewPreferenciaController(db *sql.DB) *PreferenciaController {
+	return &PreferenciaController{
+		Repo:          repositories.NewPreferenciaRepository(db),
+		ProfessorRepo: repositories.NewProfessorRepository(db),
+	}
+}
+
+// NewTurnoController cria um novo controlador de turnos
+func NewTurnoController(db *sql.DB) *TurnoController {
+	return &TurnoController{
+		Repo: repositories.NewTurnoRepository(db),
+	}
+}
+
+// NewDisciplinaController cria um novo controlador de disciplinas
+func NewDisciplinaController(db *sql.DB) *DisciplinaController {
+	return &DisciplinaController{
+		Repo:          repositories.NewDisciplinaRepository(db),
+		ProfessorRepo: repositories.NewProfessorRepository(db),
+	}
+}
+
+// NewCursoController cria um novo controlador de cursos
+func NewCursoController(db *sql.DB) *CursoController {
+	return &CursoController{
+		Repo: repositories.NewCursoRepository(db),
+	}
+}
+
+// NewCampusController cria um novo controlador de campi
+func NewCampusController(db *sql.DB) *CampusController {
+	return &CampusController{
+		Repo:      repositories.NewCampusRepository(db),
+		BlocoRepo: repositories.NewBlocoRepository(db),
+	}
+}
+
+// NewBlocoController cria um novo controlador de blocos
+func NewBlocoController(db *sql.DB) *BlocoController {
+	return &BlocoController{
+		Repo: repositories.NewBlocoRepository(db),
+	}
+}
+
+// NewPeriodoLetivoController cria um novo controlador de períodos letivos
+func NewPeriodoLetivoController(db *sql.DB) *PeriodoLetivoController {
+	return &PeriodoLetivoController{
+		Repo: repositories.NewPeriodoLetivoRepository(db),
+	}
+}
+
+// NewDiaNaoLetivoController cria um novo controlador de dias não letivos
+func NewDiaNaoLetivoController(db *sql.DB) *DiaNaoLetivoController {
+	return &DiaNaoLetivoController{
+		Repo: repositories.NewDiaNaoLetivoRepository(db),
+	}
+}
+
+// NewExcecaoAlocacaoController cria um novo controlador de exceções de alocações
+func NewExcecaoAlocacaoController(db *sql.DB) *ExcecaoAlocacaoController {
+	return &ExcecaoAlocacaoController{
+		Repo:         repositories.NewExcecaoAlocacaoRepository(db),
+		AlocacaoRepo: repositories.NewAlocacaoRepository(db),
+	}
+}
+
 // SetupRoutes configura todas as rotas da API
 func SetupRoutes(r *mux.Router, db *sql.DB) {
 	// Inicializar controladores
@@ -68,6 +213,17 @@ func SetupRoutes(r *mux.Router, db *sql.DB) {
 	salaController := NewSalaController(db)
 	turmaController := NewTurmaController(db)
 	alocacaoController := NewAlocacaoController(db)
+	disponibilidadeController := NewDisponibilidadeController(db)
+	bloqueioSalaController := NewBloqueioSalaController(db)
+	preferenciaController := NewPreferenciaController(db)
+	turnoController := NewTurnoController(db)
+	disciplinaController := NewDisciplinaController(db)
+	cursoController := NewCursoController(db)
+	campusController := NewCampusController(db)
+	blocoController := NewBlocoController(db)
+	periodoLetivoController := NewPeriodoLetivoController(db)
+	diaNaoLetivoController := NewDiaNaoLetivoController(db)
+	excecaoAlocacaoController := NewExcecaoAlocacaoController(db)
 
 	// Rotas para professores
 	r.HandleFunc("/api/professores", professorController.GetAllProfessores).Methods("GET")
@@ -76,19 +232,100 @@ func SetupRoutes(r *mux.Router, db *sql.DB) {
 	r.HandleFunc("/api/professores/{id}", professorController.UpdateProfessor).Methods("PUT")
 	r.HandleFunc("/api/professores/{id}", professorController.DeleteProfessor).Methods("DELETE")
 
+	// Rotas para disponibilidade dos professores
+	r.HandleFunc("/api/professores/{id}/disponibilidade", disponibilidadeController.GetDisponibilidades).Methods("GET")
+	r.HandleFunc("/api/professores/{id}/disponibilidade/{disponibilidadeId}", disponibilidadeController.GetDisponibilidade).Methods("GET")
+	r.HandleFunc("/api/professores/{id}/disponibilidade", disponibilidadeController.CreateDisponibilidade).Methods("POST")
+	r.HandleFunc("/api/professores/{id}/disponibilidade/{disponibilidadeId}", disponibilidadeController.UpdateDisponibilidade).Methods("PUT")
+	r.HandleFunc("/api/professores/{id}/disponibilidade/{disponibilidadeId}", disponibilidadeController.DeleteDisponibilidade).Methods("DELETE")
+
+	// Rotas para preferências dos professores
+	r.HandleFunc("/api/professores/{id}/preferencias", preferenciaController.GetPreferencias).Methods("GET")
+	r.HandleFunc("/api/professores/{id}/preferencias", preferenciaController.SavePreferencias).Methods("PUT")
+	r.HandleFunc("/api/professores/{id}/preferencias", preferenciaController.DeletePreferencias).Methods("DELETE")
+
 	// Rotas para salas
 	r.HandleFunc("/api/salas", salaController.GetAllSalas).Methods("GET")
+	r.HandleFunc("/api/salas/livres", alocacaoController.BuscarSalasLivres).Methods("GET")
 	r.HandleFunc("/api/salas/{id}", salaController.GetSala).Methods("GET")
 	r.HandleFunc("/api/salas", salaController.CreateSala).Methods("POST")
 	r.HandleFunc("/api/salas/{id}", salaController.UpdateSala).Methods("PUT")
 	r.HandleFunc("/api/salas/{id}", salaController.DeleteSala).Methods("DELETE")
 
+	// Rotas para bloqueios de salas
+	r.HandleFunc("/api/salas/{id}/bloqueios", bloqueioSalaController.GetBloqueios).Methods("GET")
+	r.HandleFunc("/api/salas/{id}/bloqueios/{bloqueioId}", bloqueioSalaController.GetBloqueio).Methods("GET")
+	r.HandleFunc("/api/salas/{id}/bloqueios", bloqueioSalaController.CreateBloqueio).Methods("POST")
+	r.HandleFunc("/api/salas/{id}/bloqueios/{bloqueioId}", bloqueioSalaController.UpdateBloqueio).Methods("PUT")
+	r.HandleFunc("/api/salas/{id}/bloqueios/{bloqueioId}", bloqueioSalaController.DeleteBloqueio).Methods("DELETE")
+
 	// Rotas para turmas
+	// Rotas para as disciplinas dos professores
+	r.HandleFunc("/api/professores/{id}/disciplinas", disciplinaController.GetDisciplinasDoProfessor).Methods("GET")
+	r.HandleFunc("/api/professores/{id}/disciplinas/{disciplinaId}", disciplinaController.VincularProfessor).Methods("PUT")
+	r.HandleFunc("/api/professores/{id}/disciplinas/{disciplinaId}", disciplinaController.DesvincularProfessor).Methods("DELETE")
+
+	// Rotas para disciplinas
+	r.HandleFunc("/api/disciplinas", disciplinaController.GetAllDisciplinas).Methods("GET")
+	r.HandleFunc("/api/disciplinas/{id}", disciplinaController.GetDisciplina).Methods("GET")
+	r.HandleFunc("/api/disciplinas", disciplinaController.CreateDisciplina).Methods("POST")
+	r.HandleFunc("/api/disciplinas/{id}", disciplinaController.UpdateDisciplina).Methods("PUT")
+	r.HandleFunc("/api/disciplinas/{id}", disciplinaController.DeleteDisciplina).Methods("DELETE")
+	r.HandleFunc("/api/disciplinas/{id}/professores", disciplinaController.GetProfessoresDaDisciplina).Methods("GET")
+
+	// Rotas para cursos
+	r.HandleFunc("/api/cursos", cursoController.GetAllCursos).Methods("GET")
+	r.HandleFunc("/api/cursos/{id}", cursoController.GetCurso).Methods("GET")
+	r.HandleFunc("/api/cursos", cursoController.CreateCurso).Methods("POST")
+	r.HandleFunc("/api/cursos/{id}", cursoController.UpdateCurso).Methods("PUT")
+	r.HandleFunc("/api/cursos/{id}", cursoController.DeleteCurso).Methods("DELETE")
+
+	// Rotas para campi
+	r.HandleFunc("/api/campi", campusController.GetAllCampi).Methods("GET")
+	r.HandleFunc("/api/campi/{id}", campusController.GetCampus).Methods("GET")
+	r.HandleFunc("/api/campi", campusController.CreateCampus).Methods("POST")
+	r.HandleFunc("/api/campi/{id}", campusController.UpdateCampus).Methods("PUT")
+	r.HandleFunc("/api/campi/{id}", campusController.DeleteCampus).Methods("DELETE")
+	r.HandleFunc("/api/campi/{id}/blocos", campusController.GetBlocosDoCampus).Methods("GET")
+
+	// Rotas para blocos e tempos de deslocamento entre blocos
+	r.HandleFunc("/api/blocos", blocoController.GetAllBlocos).Methods("GET")
+	r.HandleFunc("/api/blocos/{id}", blocoController.GetBloco).Methods("GET")
+	r.HandleFunc("/api/blocos", blocoController.CreateBloco).Methods("POST")
+	r.HandleFunc("/api/blocos/{id}", blocoController.UpdateBloco).Methods("PUT")
+	r.HandleFunc("/api/blocos/{id}", blocoController.DeleteBloco).Methods("DELETE")
+	r.HandleFunc("/api/deslocamentos", blocoController.GetDeslocamentos).Methods("GET")
+	r.HandleFunc("/api/deslocamentos", blocoController.SalvarDeslocamentos).Methods("PUT")
+	r.HandleFunc("/api/deslocamentos/{id}/{destinoId}", blocoController.RemoverDeslocamento).Methods("DELETE")
+
+	// Rotas para períodos letivos
+	r.HandleFunc("/api/periodos-letivos", periodoLetivoController.GetAllPeriodosLetivos).Methods("GET")
+	r.HandleFunc("/api/periodos-letivos/atual", periodoLetivoController.GetPeriodoLetivoAtual).Methods("GET")
+	r.HandleFunc("/api/periodos-letivos/{id}", periodoLetivoController.GetPeriodoLetivo).Methods("GET")
+	r.HandleFunc("/api/periodos-letivos", periodoLetivoController.CreatePeriodoLetivo).Methods("POST")
+	r.HandleFunc("/api/periodos-letivos/{id}", periodoLetivoController.UpdatePeriodoLetivo).Methods("PUT")
+	r.HandleFunc("/api/periodos-letivos/{id}", periodoLetivoController.DeletePeriodoLetivo).Methods("DELETE")
+
+	// Rotas para o calendário de dias não letivos
+	r.HandleFunc("/api/dias-nao-letivos", diaNaoLetivoController.GetAllDiasNaoLetivos).Methods("GET")
+	r.HandleFunc("/api/dias-nao-letivos/{id}", diaNaoLetivoController.GetDiaNaoLetivo).Methods("GET")
+	r.HandleFunc("/api/dias-nao-letivos", diaNaoLetivoController.CreateDiaNaoLetivo).Methods("POST")
+	r.HandleFunc("/api/dias-nao-letivos/{id}", diaNaoLetivoController.UpdateDiaNaoLetivo).Methods("PUT")
+	r.HandleFunc("/api/dias-nao-letivos/{id}", diaNaoLetivoController.DeleteDiaNaoLetivo).Methods("DELETE")
+
+	// Rotas para turnos
+	r.HandleFunc("/api/turnos", turnoController.GetAllTurnos).Methods("GET")
+	r.HandleFunc("/api/turnos/{id}", turnoController.GetTurno).Methods("GET")
+	r.HandleFunc("/api/turnos", turnoController.CreateTurno).Methods("POST")
+	r.HandleFunc("/api/turnos/{id}", turnoController.UpdateTurno).Methods("PUT")
+	r.HandleFunc("/api/turnos/{id}", turnoController.DeleteTurno).Methods("DELETE")
+
 	r.HandleFunc("/api/turmas", turmaController.GetAllTurmas).Methods("GET")
 	r.HandleFunc("/api/turmas/{id}", turmaController.GetTurma).Methods("GET")
 	r.HandleFunc("/api/turmas", turmaController.CreateTurma).Methods("POST")
 	r.HandleFunc("/api/turmas/{id}", turmaController.UpdateTurma).Methods("PUT")
 	r.HandleFunc("/api/turmas/{id}", turmaController.DeleteTurma).Methods("DELETE")
+	r.HandleFunc("/api/turmas/{id}/carga-horaria", turmaController.GetCargaHoraria).Methods("GET")
 
 	// Rotas para alocações
 	r.HandleFunc("/api/alocacoes", alocacaoController.GetAllAlocacoes).Methods("GET")
@@ -97,11 +334,23 @@ func SetupRoutes(r *mux.Router, db *sql.DB) {
 	r.HandleFunc("/api/alocacoes/{id}", alocacaoController.UpdateAlocacao).Methods("PUT")
 	r.HandleFunc("/api/alocacoes/{id}", alocacaoController.DeleteAlocacao).Methods("DELETE")
 	r.HandleFunc("/api/alocacoes/automatico", alocacaoController.OrganizarAlocacoesAutomaticas).Methods("POST")
+	r.HandleFunc("/api/alocacoes/grade-semanal", alocacaoController.GerarGradeSemanal).Methods("POST")
+
+	// Rotas para as exceções de uma alocação em datas específicas
+	r.HandleFunc("/api/alocacoes/{id}/excecoes", excecaoAlocacaoController.GetExcecoes).Methods("GET")
+	r.HandleFunc("/api/alocacoes/{id}/excecoes/{excecaoId}", excecaoAlocacaoController.GetExcecao).Methods("GET")
+	r.HandleFunc("/api/alocacoes/{id}/excecoes", excecaoAlocacaoController.CreateExcecao).Methods("POST")
+	r.HandleFunc("/api/alocacoes/{id}/excecoes/{excecaoId}", excecaoAlocacaoController.UpdateExcecao).Methods("PUT")
+	r.HandleFunc("/api/alocacoes/{id}/excecoes/{excecaoId}", excecaoAlocacaoController.DeleteExcecao).Methods("DELETE")
+
+	// Rota para a agenda das aulas por data
+	r.HandleFunc("/api/agenda", alocacaoController.GetAgenda).Methods("GET")
 
 	// Rotas especiais para alocações
 	r.HandleFunc("/api/alocacoes/sala/{id}", alocacaoController.GetAlocacoesBySala).Methods("GET")
 	r.HandleFunc("/api/alocacoes/professor/{id}", alocacaoController.GetAlocacoesByProfessor).Methods("GET")
 	r.HandleFunc("/api/alocacoes/turma/{id}", alocacaoController.GetAlocacoesByTurma).Methods("GET")
+	r.HandleFunc("/api/alocacoes/disciplina/{id}", alocacaoController.GetAlocacoesByDisciplina).Methods("GET")
 }
 
 // ===== Métodos do ProfessorController =====
@@ -152,6 +401,10 @@ func (c *ProfessorController) CreateProfessor(w http.ResponseWriter, r *http.Req
 
 	professor, err = c.Repo.Create(professor)
 	if err != nil {
+		if errors.Is(err, repositories.ErrDisciplinaInvalida) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -180,6 +433,10 @@ func (c *ProfessorController) UpdateProfessor(w http.ResponseWriter, r *http.Req
 	professor.ID = id
 	err = c.Repo.Update(professor)
 	if err != nil {
+		if errors.Is(err, repositories.ErrDisciplinaInvalida) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -205,200 +462,224 @@ func (c *ProfessorController) DeleteProfessor(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// ===== Métodos do SalaController =====
+// ===== Métodos do DisponibilidadeController =====
 
-// GetAllSalas retorna todas as salas
-func (c *SalaController) GetAllSalas(w http.ResponseWriter, r *http.Request) {
-	salas, err := c.Repo.GetAll()
+// idsAninhados lê o ID do recurso pai ("id") e o ID do recurso aninhado de uma rota como
+// /api/professores/{id}/disponibilidade/{disponibilidadeId}. Em caso de erro, a resposta já foi
+// enviada e o retorno ok é false.
+func idsAninhados(w http.ResponseWriter, r *http.Request, chave string) (int, int, bool) {
+	vars := mux.Vars(r)
+	paiID, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return 0, 0, false
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(salas)
+	id, err := strconv.Atoi(vars[chave])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return paiID, id, true
 }
 
-// GetSala retorna uma sala pelo ID
-func (c *SalaController) GetSala(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+// professorDaRota lê o ID do professor da rota e verifica se ele existe. Em caso de erro, a resposta
+// já foi enviada e o retorno ok é false.
+func professorDaRota(w http.ResponseWriter, r *http.Request, professorRepo *repositories.ProfessorRepository) (int, bool) {
+	professorID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		http.Error(w, "ID inválido", http.StatusBadRequest)
-		return
+		return 0, false
 	}
 
-	sala, err := c.Repo.GetByID(id)
+	_, err = professorRepo.GetByID(professorID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			http.Error(w, "Sala não encontrada", http.StatusNotFound)
-			return
+			http.Error(w, "Professor não encontrado", http.StatusNotFound)
+			return 0, false
 		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, false
+	}
+
+	return professorID, true
+}
+
+// GetDisponibilidades retorna as janelas de disponibilidade de um professor
+func (c *DisponibilidadeController) GetDisponibilidades(w http.ResponseWriter, r *http.Request) {
+	professorID, ok := professorDaRota(w, r, c.ProfessorRepo)
+	if !ok {
+		return
+	}
+
+	disponibilidades, err := c.Repo.GetByProfessor(professorID)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(sala)
+	json.NewEncoder(w).Encode(disponibilidades)
 }
 
-// CreateSala cria uma nova sala
-func (c *SalaController) CreateSala(w http.ResponseWriter, r *http.Request) {
-	var sala models.Sala
-	err := json.NewDecoder(r.Body).Decode(&sala)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+// GetDisponibilidade retorna uma janela de disponibilidade de um professor
+func (c *DisponibilidadeController) GetDisponibilidade(w http.ResponseWriter, r *http.Request) {
+	professorID, id, ok := idsAninhados(w, r, "disponibilidadeId")
+	if !ok {
 		return
 	}
 
-	sala, err = c.Repo.Create(sala)
+	disponibilidade, err := c.Repo.GetByID(professorID, id)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Disponibilidade não encontrada", http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(sala)
+	json.NewEncoder(w).Encode(disponibilidade)
 }
 
-// UpdateSala atualiza uma sala existente
-func (c *SalaController) UpdateSala(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+// CreateDisponibilidade cria uma nova janela de disponibilidade para o professor
+func (c *DisponibilidadeController) CreateDisponibilidade(w http.ResponseWriter, r *http.Request) {
+	professorID, ok := professorDaRota(w, r, c.ProfessorRepo)
+	if !ok {
 		return
 	}
 
-	var sala models.Sala
-	err = json.NewDecoder(r.Body).Decode(&sala)
+	var disponibilidade models.DisponibilidadeProfessor
+	err := json.NewDecoder(r.Body).Decode(&disponibilidade)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	sala.ID = id
-	err = c.Repo.Update(sala)
+	disponibilidade.ProfessorID = professorID
+	disponibilidade, err = c.Repo.Create(disponibilidade)
 	if err != nil {
+		if errors.Is(err, repositories.ErrDisponibilidadeInvalida) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(disponibilidade)
 }
 
-// DeleteSala remove uma sala pelo ID
-func (c *SalaController) DeleteSala(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+// UpdateDisponibilidade atualiza uma janela de disponibilidade do professor
+func (c *DisponibilidadeController) UpdateDisponibilidade(w http.ResponseWriter, r *http.Request) {
+	professorID, id, ok := idsAninhados(w, r, "disponibilidadeId")
+	if !ok {
 		return
 	}
 
-	err = c.Repo.Delete(id)
+	var disponibilidade models.DisponibilidadeProfessor
+	err := json.NewDecoder(r.Body).Decode(&disponibilidade)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// ===== Métodos do TurmaController =====
-
-// GetAllTurmas retorna todas as turmas
-func (c *TurmaController) GetAllTurmas(w http.ResponseWriter, r *http.Request) {
-	turmas, err := c.Repo.GetAll()
+	disponibilidade.ID = id
+	disponibilidade.ProfessorID = professorID
+	err = c.Repo.Update(disponibilidade)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, repositories.ErrDisponibilidadeInvalida):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err == sql.ErrNoRows:
+			http.Error(w, "Disponibilidade não encontrada", http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(turmas)
+	w.WriteHeader(http.StatusOK)
 }
 
-// GetTurma retorna uma turma pelo ID
-func (c *TurmaController) GetTurma(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+// DeleteDisponibilidade remove uma janela de disponibilidade do professor
+func (c *DisponibilidadeController) DeleteDisponibilidade(w http.ResponseWriter, r *http.Request) {
+	professorID, id, ok := idsAninhados(w, r, "disponibilidadeId")
+	if !ok {
 		return
 	}
 
-	turma, err := c.Repo.GetByID(id)
+	err := c.Repo.Delete(professorID, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			http.Error(w, "Turma não encontrada", http.StatusNotFound)
+			http.Error(w, "Disponibilidade não encontrada", http.StatusNotFound)
 			return
 		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(turma)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// CreateTurma cria uma nova turma
-func (c *TurmaController) CreateTurma(w http.ResponseWriter, r *http.Request) {
-	var turma models.Turma
-	err := json.NewDecoder(r.Body).Decode(&turma)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+// ===== Métodos do PreferenciaController =====
+
+// GetPreferencias retorna as preferências de um professor
+func (c *PreferenciaController) GetPreferencias(w http.ResponseWriter, r *http.Request) {
+	professorID, ok := professorDaRota(w, r, c.ProfessorRepo)
+	if !ok {
 		return
 	}
 
-	turma, err = c.Repo.Create(turma)
+	preferencias, err := c.Repo.GetByProfessor(professorID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(turma)
+	json.NewEncoder(w).Encode(preferencias)
 }
 
-// UpdateTurma atualiza uma turma existente
-func (c *TurmaController) UpdateTurma(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+// SavePreferencias grava as preferências de um professor, substituindo as anteriores
+func (c *PreferenciaController) SavePreferencias(w http.ResponseWriter, r *http.Request) {
+	professorID, ok := professorDaRota(w, r, c.ProfessorRepo)
+	if !ok {
 		return
 	}
 
-	var turma models.Turma
-	err = json.NewDecoder(r.Body).Decode(&turma)
+	var preferencias models.PreferenciaProfessor
+	err := json.NewDecoder(r.Body).Decode(&preferencias)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	turma.ID = id
-	err = c.Repo.Update(turma)
+	preferencias.ProfessorID = professorID
+	preferencias, err = c.Repo.Save(preferencias)
 	if err != nil {
+		if errors.Is(err, repositories.ErrPreferenciaInvalida) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preferencias)
 }
 
-// DeleteTurma remove uma turma pelo ID
-func (c *TurmaController) DeleteTurma(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+// DeletePreferencias remove as preferências de um professor
+func (c *PreferenciaController) DeletePreferencias(w http.ResponseWriter, r *http.Request) {
+	professorID, ok := professorDaRota(w, r, c.ProfessorRepo)
+	if !ok {
 		return
 	}
 
-	err = c.Repo.Delete(id)
+	err := c.Repo.Delete(professorID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -407,22 +688,22 @@ func (c *TurmaController) DeleteTurma(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// ===== Métodos do AlocacaoController =====
+// ===== Métodos do SalaController =====
 
-// GetAllAlocacoes retorna todas as alocações
-func (c *AlocacaoController) GetAllAlocacoes(w http.ResponseWriter, r *http.Request) {
-	alocacoes, err := c.Repo.GetAll()
+// GetAllSalas retorna todas as salas
+func (c *SalaController) GetAllSalas(w http.ResponseWriter, r *http.Request) {
+	salas, err := c.Repo.GetAll()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(alocacoes)
+	json.NewEncoder(w).Encode(salas)
 }
 
-// GetAlocacao retorna uma alocação pelo ID
-func (c *AlocacaoController) GetAlocacao(w http.ResponseWriter, r *http.Request) {
+// GetSala retorna uma sala pelo ID
+func (c *SalaController) GetSala(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
@@ -430,10 +711,10 @@ func (c *AlocacaoController) GetAlocacao(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	alocacao, err := c.Repo.GetByID(id)
+	sala, err := c.Repo.GetByID(id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			http.Error(w, "Alocação não encontrada", http.StatusNotFound)
+			http.Error(w, "Sala não encontrada", http.StatusNotFound)
 			return
 		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -441,33 +722,35 @@ func (c *AlocacaoController) GetAlocacao(w http.ResponseWriter, r *http.Request)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(alocacao)
+	json.NewEncoder(w).Encode(sala)
 }
 
-// CreateAlocacao cria uma nova alocação
-func (c *AlocacaoController) CreateAlocacao(w http.ResponseWriter, r *http.Request) {
-	var alocacao models.Alocacao
-	err := json.NewDecoder(r.Body).Decode(&alocacao)
+// CreateSala cria uma nova sala
+func (c *SalaController) CreateSala(w http.ResponseWriter, r *http.Request) {
+	var sala models.Sala
+	err := json.NewDecoder(r.Body).Decode(&sala)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	alocacao, err = c.Repo.Create(alocacao)
+	sala, err = c.Repo.Create(sala)
 	if err != nil {
+		if errors.Is(err, repositories.ErrBlocoInvalido) || errors.Is(err, repositories.ErrEquipamentosInvalidos) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	go infra.SendEmailOnAlocacaoSuccess(alocacao)
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(alocacao)
+	json.NewEncoder(w).Encode(sala)
 }
 
-// UpdateAlocacao atualiza uma alocação existente
-func (c *AlocacaoController) UpdateAlocacao(w http.ResponseWriter, r *http.Request) {
+// UpdateSala atualiza uma sala existente
+func (c *SalaController) UpdateSala(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
@@ -475,16 +758,20 @@ func (c *AlocacaoController) UpdateAlocacao(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var alocacao models.Alocacao
-	err = json.NewDecoder(r.Body).Decode(&alocacao)
+	var sala models.Sala
+	err = json.NewDecoder(r.Body).Decode(&sala)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	alocacao.ID = id
-	err = c.Repo.Update(alocacao)
+	sala.ID = id
+	err = c.Repo.Update(sala)
 	if err != nil {
+		if errors.Is(err, repositories.ErrBlocoInvalido) || errors.Is(err, repositories.ErrEquipamentosInvalidos) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -492,8 +779,8 @@ func (c *AlocacaoController) UpdateAlocacao(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
-// DeleteAlocacao remove uma alocação pelo ID
-func (c *AlocacaoController) DeleteAlocacao(w http.ResponseWriter, r *http.Request) {
+// DeleteSala remove uma sala pelo ID
+func (c *SalaController) DeleteSala(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
@@ -510,21 +797,1590 @@ func (c *AlocacaoController) DeleteAlocacao(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// GetAlocacoesBySala retorna todas as alocações de uma sala específica
-func (c *AlocacaoController) GetAlocacoesBySala(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+// ===== Métodos do BloqueioSalaController =====
+
+// salaDaRota lê o ID da sala da rota e verifica se ela existe. Em caso de erro, a resposta já foi
+// enviada e o retorno ok é false.
+func (c *BloqueioSalaController) salaDaRota(w http.ResponseWriter, r *http.Request) (int, bool) {
+	salaID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return 0, false
+	}
+
+	_, err = c.SalaRepo.GetByID(salaID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Sala não encontrada", http.StatusNotFound)
+			return 0, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, false
+	}
+
+	return salaID, true
+}
+
+// GetBloqueios retorna os bloqueios de uma sala
+func (c *BloqueioSalaController) GetBloqueios(w http.ResponseWriter, r *http.Request) {
+	salaID, ok := c.salaDaRota(w, r)
+	if !ok {
 		return
 	}
 
-	alocacoes, err := c.Repo.GetBySalaID(id)
+	bloqueios, err := c.Repo.GetBySala(salaID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bloqueios)
+}
+
+// GetBloqueio retorna um bloqueio de uma sala
+func (c *BloqueioSalaController) GetBloqueio(w http.ResponseWriter, r *http.Request) {
+	salaID, id, ok := idsAninhados(w, r, "bloqueioId")
+	if !ok {
+		return
+	}
+
+	bloqueio, err := c.Repo.GetByID(salaID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Bloqueio não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bloqueio)
+}
+
+// CreateBloqueio cria um novo bloqueio para a sala. A resposta lista as alocações existentes
+// que caem no bloqueio.
+func (c *BloqueioSalaController) CreateBloqueio(w http.ResponseWriter, r *http.Request) {
+	salaID, ok := c.salaDaRota(w, r)
+	if !ok {
+		return
+	}
+
+	var bloqueio models.BloqueioSala
+	err := json.NewDecoder(r.Body).Decode(&bloqueio)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bloqueio.SalaID = salaID
+	bloqueio, err = c.Repo.Create(bloqueio)
+	if err != nil {
+		if errors.Is(err, repositories.ErrBloqueioInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bloqueio)
+}
+
+// UpdateBloqueio atualiza um bloqueio da sala. A resposta lista as alocações existentes
+// que caem no bloqueio.
+func (c *BloqueioSalaController) UpdateBloqueio(w http.ResponseWriter, r *http.Request) {
+	salaID, id, ok := idsAninhados(w, r, "bloqueioId")
+	if !ok {
+		return
+	}
+
+	var bloqueio models.BloqueioSala
+	err := json.NewDecoder(r.Body).Decode(&bloqueio)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bloqueio.ID = id
+	bloqueio.SalaID = salaID
+	bloqueio, err = c.Repo.Update(bloqueio)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrBloqueioInvalido):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err == sql.ErrNoRows:
+			http.Error(w, "Bloqueio não encontrado", http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bloqueio)
+}
+
+// DeleteBloqueio remove um bloqueio da sala
+func (c *BloqueioSalaController) DeleteBloqueio(w http.ResponseWriter, r *http.Request) {
+	salaID, id, ok := idsAninhados(w, r, "bloqueioId")
+	if !ok {
+		return
+	}
+
+	err := c.Repo.Delete(salaID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Bloqueio não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== Métodos do TurmaController =====
+
+// GetAllTurmas retorna todas as turmas
+func (c *TurmaController) GetAllTurmas(w http.ResponseWriter, r *http.Request) {
+	turmas, err := c.Repo.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(turmas)
+}
+
+// GetTurma retorna uma turma pelo ID
+func (c *TurmaController) GetTurma(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	turma, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Turma não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(turma)
+}
+
+// CreateTurma cria uma nova turma
+func (c *TurmaController) CreateTurma(w http.ResponseWriter, r *http.Request) {
+	var turma models.Turma
+	err := json.NewDecoder(r.Body).Decode(&turma)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	turma, err = c.Repo.Create(turma)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDisciplinaInvalida) || errors.Is(err, repositories.ErrCursoInvalido) ||
+			errors.Is(err, repositories.ErrEquipamentosInvalidos) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(turma)
+}
+
+// UpdateTurma atualiza uma turma existente
+func (c *TurmaController) UpdateTurma(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var turma models.Turma
+	err = json.NewDecoder(r.Body).Decode(&turma)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	turma.ID = id
+	err = c.Repo.Update(turma)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDisciplinaInvalida) || errors.Is(err, repositories.ErrCursoInvalido) ||
+			errors.Is(err, repositories.ErrEquipamentosInvalidos) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetCargaHoraria retorna a carga horária semanal de cada disciplina da turma, derivada da grade
+// curricular do seu curso no seu semestre
+func (c *TurmaController) GetCargaHoraria(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := c.Repo.GetByID(id); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Turma não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cargas, err := c.Repo.GetCargaHoraria(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cargas)
+}
+
+// DeleteTurma remove uma turma pelo ID
+func (c *TurmaController) DeleteTurma(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== Métodos do DisciplinaController =====
+
+// GetAllDisciplinas retorna todas as disciplinas
+func (c *DisciplinaController) GetAllDisciplinas(w http.ResponseWriter, r *http.Request) {
+	disciplinas, err := c.Repo.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disciplinas)
+}
+
+// GetDisciplina retorna uma disciplina pelo ID
+func (c *DisciplinaController) GetDisciplina(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	disciplina, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Disciplina não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disciplina)
+}
+
+// CreateDisciplina cria uma nova disciplina
+func (c *DisciplinaController) CreateDisciplina(w http.ResponseWriter, r *http.Request) {
+	var disciplina models.Disciplina
+	err := json.NewDecoder(r.Body).Decode(&disciplina)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	disciplina, err = c.Repo.Create(disciplina)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDisciplinaInvalida) || errors.Is(err, repositories.ErrEquipamentosInvalidos) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(disciplina)
+}
+
+// UpdateDisciplina atualiza uma disciplina existente
+func (c *DisciplinaController) UpdateDisciplina(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var disciplina models.Disciplina
+	err = json.NewDecoder(r.Body).Decode(&disciplina)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	disciplina.ID = id
+	err = c.Repo.Update(disciplina)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDisciplinaInvalida) || errors.Is(err, repositories.ErrEquipamentosInvalidos) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteDisciplina remove uma disciplina pelo ID
+func (c *DisciplinaController) DeleteDisciplina(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetProfessoresDaDisciplina retorna os professores que lecionam uma disciplina
+func (c *DisciplinaController) GetProfessoresDaDisciplina(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := c.Repo.GetByID(id); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Disciplina não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	professores, err := c.Repo.GetProfessores(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(professores)
+}
+
+// GetDisciplinasDoProfessor retorna as disciplinas que um professor leciona
+func (c *DisciplinaController) GetDisciplinasDoProfessor(w http.ResponseWriter, r *http.Request) {
+	professorID, ok := professorDaRota(w, r, c.ProfessorRepo)
+	if !ok {
+		return
+	}
+
+	disciplinas, err := c.Repo.GetByProfessor(professorID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disciplinas)
+}
+
+// VincularProfessor associa uma disciplina a um professor
+func (c *DisciplinaController) VincularProfessor(w http.ResponseWriter, r *http.Request) {
+	if _, ok := professorDaRota(w, r, c.ProfessorRepo); !ok {
+		return
+	}
+	professorID, disciplinaID, ok := idsAninhados(w, r, "disciplinaId")
+	if !ok {
+		return
+	}
+
+	err := c.Repo.VincularProfessor(professorID, disciplinaID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDisciplinaInvalida) {
+			http.Error(w, "Disciplina não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DesvincularProfessor remove a associação entre um professor e uma disciplina
+func (c *DisciplinaController) DesvincularProfessor(w http.ResponseWriter, r *http.Request) {
+	professorID, disciplinaID, ok := idsAninhados(w, r, "disciplinaId")
+	if !ok {
+		return
+	}
+
+	err := c.Repo.DesvincularProfessor(professorID, disciplinaID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "O professor não leciona esta disciplina", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== Métodos do CursoController =====
+
+// GetAllCursos retorna todos os cursos com as grades curriculares
+func (c *CursoController) GetAllCursos(w http.ResponseWriter, r *http.Request) {
+	cursos, err := c.Repo.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cursos)
+}
+
+// GetCurso retorna um curso pelo ID com a grade curricular
+func (c *CursoController) GetCurso(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	curso, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Curso não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(curso)
+}
+
+// CreateCurso cria um novo curso com a grade curricular
+func (c *CursoController) CreateCurso(w http.ResponseWriter, r *http.Request) {
+	var curso models.Curso
+	err := json.NewDecoder(r.Body).Decode(&curso)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	curso, err = c.Repo.Create(curso)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCursoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(curso)
+}
+
+// UpdateCurso atualiza um curso existente e substitui a grade curricular
+func (c *CursoController) UpdateCurso(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var curso models.Curso
+	err = json.NewDecoder(r.Body).Decode(&curso)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	curso.ID = id
+	err = c.Repo.Update(curso)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Curso não encontrado", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repositories.ErrCursoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteCurso remove um curso pelo ID
+func (c *CursoController) DeleteCurso(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== Métodos do CampusController =====
+
+// GetAllCampi retorna todos os campi
+func (c *CampusController) GetAllCampi(w http.ResponseWriter, r *http.Request) {
+	campi, err := c.Repo.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campi)
+}
+
+// GetCampus retorna um campus pelo ID
+func (c *CampusController) GetCampus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	campus, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Campus não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campus)
+}
+
+// CreateCampus cria um novo campus
+func (c *CampusController) CreateCampus(w http.ResponseWriter, r *http.Request) {
+	var campus models.Campus
+	err := json.NewDecoder(r.Body).Decode(&campus)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	campus, err = c.Repo.Create(campus)
+	if err != nil {
+		if errors.Is(err, repositories.ErrBlocoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(campus)
+}
+
+// UpdateCampus atualiza um campus existente
+func (c *CampusController) UpdateCampus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var campus models.Campus
+	err = json.NewDecoder(r.Body).Decode(&campus)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	campus.ID = id
+	err = c.Repo.Update(campus)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Campus não encontrado", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repositories.ErrBlocoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteCampus remove um campus pelo ID
+func (c *CampusController) DeleteCampus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBlocosDoCampus retorna os blocos de um campus
+func (c *CampusController) GetBlocosDoCampus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := c.Repo.GetByID(id); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Campus não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	blocos, err := c.BlocoRepo.GetByCampus(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocos)
+}
+
+// ===== Métodos do BlocoController =====
+
+// GetAllBlocos retorna todos os blocos
+func (c *BlocoController) GetAllBlocos(w http.ResponseWriter, r *http.Request) {
+	blocos, err := c.Repo.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(blocos)
+}
+
+// GetBloco retorna um bloco pelo ID
+func (c *BlocoController) GetBloco(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	bloco, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Bloco não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bloco)
+}
+
+// CreateBloco cria um novo bloco
+func (c *BlocoController) CreateBloco(w http.ResponseWriter, r *http.Request) {
+	var bloco models.Bloco
+	err := json.NewDecoder(r.Body).Decode(&bloco)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bloco, err = c.Repo.Create(bloco)
+	if err != nil {
+		if errors.Is(err, repositories.ErrBlocoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bloco)
+}
+
+// UpdateBloco atualiza um bloco existente
+func (c *BlocoController) UpdateBloco(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var bloco models.Bloco
+	err = json.NewDecoder(r.Body).Decode(&bloco)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bloco.ID = id
+	err = c.Repo.Update(bloco)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Bloco não encontrado", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repositories.ErrBlocoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteBloco remove um bloco pelo ID
+func (c *BlocoController) DeleteBloco(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeslocamentos retorna a matriz de tempos de deslocamento entre blocos
+func (c *BlocoController) GetDeslocamentos(w http.ResponseWriter, r *http.Request) {
+	deslocamentos, err := c.Repo.GetDeslocamentos()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deslocamentos)
+}
+
+// SalvarDeslocamentos grava tempos de deslocamento entre pares de blocos
+func (c *BlocoController) SalvarDeslocamentos(w http.ResponseWriter, r *http.Request) {
+	var deslocamentos []models.Deslocamento
+	err := json.NewDecoder(r.Body).Decode(&deslocamentos)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.SalvarDeslocamentos(deslocamentos)
+	if err != nil {
+		if errors.Is(err, repositories.ErrBlocoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RemoverDeslocamento remove o tempo de deslocamento entre dois blocos
+func (c *BlocoController) RemoverDeslocamento(w http.ResponseWriter, r *http.Request) {
+	origemID, destinoID, ok := idsAninhados(w, r, "destinoId")
+	if !ok {
+		return
+	}
+
+	err := c.Repo.RemoverDeslocamento(origemID, destinoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Tempo de deslocamento não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== Métodos do TurnoController =====
+
+// GetAllTurnos retorna todos os turnos
+func (c *TurnoController) GetAllTurnos(w http.ResponseWriter, r *http.Request) {
+	turnos, err := c.Repo.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(turnos)
+}
+
+// GetTurno retorna um turno pelo ID
+func (c *TurnoController) GetTurno(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	turno, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Turno não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(turno)
+}
+
+// CreateTurno cria um novo turno
+func (c *TurnoController) CreateTurno(w http.ResponseWriter, r *http.Request) {
+	var turno models.Turno
+	err := json.NewDecoder(r.Body).Decode(&turno)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	turno, err = c.Repo.Create(turno)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTurnoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(turno)
+}
+
+// UpdateTurno atualiza um turno existente
+func (c *TurnoController) UpdateTurno(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var turno models.Turno
+	err = json.NewDecoder(r.Body).Decode(&turno)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	turno.ID = id
+	err = c.Repo.Update(turno)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTurnoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteTurno remove um turno pelo ID
+func (c *TurnoController) DeleteTurno(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== Métodos do PeriodoLetivoController =====
+
+// GetAllPeriodosLetivos retorna todos os períodos letivos, com o atual marcado
+func (c *PeriodoLetivoController) GetAllPeriodosLetivos(w http.ResponseWriter, r *http.Request) {
+	periodos, err := c.Repo.GetAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(periodos)
+}
+
+// GetPeriodoLetivoAtual retorna o período letivo atual, usado quando nenhum período é informado
+func (c *PeriodoLetivoController) GetPeriodoLetivoAtual(w http.ResponseWriter, r *http.Request) {
+	periodo, err := c.Repo.GetAtual()
+	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, "Nenhum período letivo cadastrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(periodo)
+}
+
+// GetPeriodoLetivo retorna um período letivo pelo ID
+func (c *PeriodoLetivoController) GetPeriodoLetivo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	periodo, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Período letivo não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(periodo)
+}
+
+// CreatePeriodoLetivo cria um novo período letivo
+func (c *PeriodoLetivoController) CreatePeriodoLetivo(w http.ResponseWriter, r *http.Request) {
+	var periodo models.PeriodoLetivo
+	err := json.NewDecoder(r.Body).Decode(&periodo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	periodo, err = c.Repo.Create(periodo)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(periodo)
+}
+
+// UpdatePeriodoLetivo atualiza um período letivo existente
+func (c *PeriodoLetivoController) UpdatePeriodoLetivo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var periodo models.PeriodoLetivo
+	err = json.NewDecoder(r.Body).Decode(&periodo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	periodo.ID = id
+	err = c.Repo.Update(periodo)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Período letivo não encontrado", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeletePeriodoLetivo remove um período letivo pelo ID; períodos com alocações não podem ser removidos
+func (c *PeriodoLetivoController) DeletePeriodoLetivo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Período letivo não encontrado", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== Métodos do DiaNaoLetivoController =====
+
+// GetAllDiasNaoLetivos retorna os dias não letivos de todos os períodos ou do informado em ?periodo_letivo_id=
+func (c *DiaNaoLetivoController) GetAllDiasNaoLetivos(w http.ResponseWriter, r *http.Request) {
+	periodoLetivoID, ok := periodoLetivoDaConsulta(w, r)
+	if !ok {
+		return
+	}
+
+	dias, err := c.Repo.GetAll(periodoLetivoID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dias)
+}
+
+// GetDiaNaoLetivo retorna um dia não letivo pelo ID
+func (c *DiaNaoLetivoController) GetDiaNaoLetivo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	dia, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Dia não letivo não encontrado", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dia)
+}
+
+// CreateDiaNaoLetivo cadastra um novo dia não letivo
+func (c *DiaNaoLetivoController) CreateDiaNaoLetivo(w http.ResponseWriter, r *http.Request) {
+	var dia models.DiaNaoLetivo
+	err := json.NewDecoder(r.Body).Decode(&dia)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dia, err = c.Repo.Create(dia)
+	if err != nil {
+		if errors.Is(err, repositories.ErrDiaNaoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dia)
+}
+
+// UpdateDiaNaoLetivo atualiza um dia não letivo existente
+func (c *DiaNaoLetivoController) UpdateDiaNaoLetivo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var dia models.DiaNaoLetivo
+	err = json.NewDecoder(r.Body).Decode(&dia)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dia.ID = id
+	err = c.Repo.Update(dia)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Dia não letivo não encontrado", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, repositories.ErrDiaNaoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteDiaNaoLetivo remove um dia não letivo pelo ID
+func (c *DiaNaoLetivoController) DeleteDiaNaoLetivo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ===== Métodos do ExcecaoAlocacaoController =====
+
+// alocacaoDaRota lê o ID da alocação da rota e verifica se ela existe. Em caso de erro, a resposta
+// já foi enviada e o retorno ok é false.
+func (c *ExcecaoAlocacaoController) alocacaoDaRota(w http.ResponseWriter, r *http.Request) (int, bool) {
+	alocacaoID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return 0, false
+	}
+
+	_, err = c.AlocacaoRepo.GetByID(alocacaoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Alocação não encontrada", http.StatusNotFound)
+			return 0, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, false
+	}
+
+	return alocacaoID, true
+}
+
+// GetExcecoes retorna as exceções de uma alocação
+func (c *ExcecaoAlocacaoController) GetExcecoes(w http.ResponseWriter, r *http.Request) {
+	alocacaoID, ok := c.alocacaoDaRota(w, r)
+	if !ok {
+		return
+	}
+
+	excecoes, err := c.Repo.GetByAlocacao(alocacaoID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(excecoes)
+}
+
+// GetExcecao retorna uma exceção de uma alocação
+func (c *ExcecaoAlocacaoController) GetExcecao(w http.ResponseWriter, r *http.Request) {
+	alocacaoID, id, ok := idsAninhados(w, r, "excecaoId")
+	if !ok {
+		return
+	}
+
+	excecao, err := c.Repo.GetByID(alocacaoID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Exceção não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(excecao)
+}
+
+// CreateExcecao registra uma exceção para uma aula da alocação: cancelamento, troca de sala ou reposição
+func (c *ExcecaoAlocacaoController) CreateExcecao(w http.ResponseWriter, r *http.Request) {
+	alocacaoID, ok := c.alocacaoDaRota(w, r)
+	if !ok {
+		return
+	}
+
+	var excecao models.ExcecaoAlocacao
+	err := json.NewDecoder(r.Body).Decode(&excecao)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	excecao.AlocacaoID = alocacaoID
+	excecao, err = c.Repo.Create(excecao)
+	if err != nil {
+		respondErroExcecao(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(excecao)
+}
+
+// UpdateExcecao atualiza uma exceção da alocação
+func (c *ExcecaoAlocacaoController) UpdateExcecao(w http.ResponseWriter, r *http.Request) {
+	alocacaoID, id, ok := idsAninhados(w, r, "excecaoId")
+	if !ok {
+		return
+	}
+
+	var excecao models.ExcecaoAlocacao
+	err := json.NewDecoder(r.Body).Decode(&excecao)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	excecao.ID = id
+	excecao.AlocacaoID = alocacaoID
+	err = c.Repo.Update(excecao)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Exceção não encontrada", http.StatusNotFound)
+			return
+		}
+		respondErroExcecao(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteExcecao remove uma exceção da alocação; a aula volta a seguir o padrão semanal
+func (c *ExcecaoAlocacaoController) DeleteExcecao(w http.ResponseWriter, r *http.Request) {
+	alocacaoID, id, ok := idsAninhados(w, r, "excecaoId")
+	if !ok {
+		return
+	}
+
+	err := c.Repo.Delete(alocacaoID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Exceção não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondErroExcecao responde com 400 para exceções inválidas e, para os conflitos e as violações
+// de regras da aula resultante, como respondErroAlocacao
+func respondErroExcecao(w http.ResponseWriter, err error) {
+	if errors.Is(err, repositories.ErrExcecaoInvalida) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	respondErroAlocacao(w, err)
+}
+
+// ===== Métodos do AlocacaoController =====
+
+// periodoLetivoDaConsulta lê o parâmetro opcional periodo_letivo_id da URL; sem ele, vale o
+// período letivo atual (zero). Responde 400 se o parâmetro for inválido.
+func periodoLetivoDaConsulta(w http.ResponseWriter, r *http.Request) (int, bool) {
+	valor := r.URL.Query().Get("periodo_letivo_id")
+	if valor == "" {
+		return 0, true
+	}
+
+	id, err := strconv.Atoi(valor)
+	if err != nil || id <= 0 {
+		http.Error(w, "periodo_letivo_id inválido", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// GetAllAlocacoes retorna todas as alocações do período letivo atual ou do informado em ?periodo_letivo_id=
+func (c *AlocacaoController) GetAllAlocacoes(w http.ResponseWriter, r *http.Request) {
+	periodoLetivoID, ok := periodoLetivoDaConsulta(w, r)
+	if !ok {
+		return
+	}
+
+	alocacoes, err := c.Repo.GetAll(periodoLetivoID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alocacoes)
+}
+
+// GetAlocacao retorna uma alocação pelo ID
+func (c *AlocacaoController) GetAlocacao(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	alocacao, err := c.Repo.GetByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Alocação não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alocacao)
+}
+
+// CreateAlocacao cria uma nova alocação
+func (c *AlocacaoController) CreateAlocacao(w http.ResponseWriter, r *http.Request) {
+	var alocacao models.Alocacao
+	err := json.NewDecoder(r.Body).Decode(&alocacao)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	alocacao, err = c.Repo.Create(alocacao)
+	if err != nil {
+		respondErroAlocacao(w, err)
+		return
+	}
+
+	go infra.SendEmailOnAlocacaoSuccess(alocacao)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(alocacao)
+}
+
+// UpdateAlocacao atualiza uma alocação existente
+func (c *AlocacaoController) UpdateAlocacao(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var alocacao models.Alocacao
+	err = json.NewDecoder(r.Body).Decode(&alocacao)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	alocacao.ID = id
+	avisos, err := c.Repo.Update(alocacao)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Alocação não encontrada", http.StatusNotFound)
+			return
+		}
+		respondErroAlocacao(w, err)
+		return
+	}
+
+	// Avisos de deslocamento entre blocos não impedem a atualização, mas são informados no corpo
+	if len(avisos) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]any{"avisos": avisos})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeleteAlocacao remove uma alocação pelo ID
+func (c *AlocacaoController) DeleteAlocacao(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.Repo.Delete(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAlocacoesBySala retorna todas as alocações de uma sala específica
+func (c *AlocacaoController) GetAlocacoesBySala(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	periodoLetivoID, ok := periodoLetivoDaConsulta(w, r)
+	if !ok {
+		return
+	}
+
+	alocacoes, err := c.Repo.GetBySalaID(id, periodoLetivoID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(alocacoes)
 }
@@ -538,8 +2394,45 @@ func (c *AlocacaoController) GetAlocacoesByProfessor(w http.ResponseWriter, r *h
 		return
 	}
 
-	alocacoes, err := c.Repo.GetByProfessorID(id)
+	periodoLetivoID, ok := periodoLetivoDaConsulta(w, r)
+	if !ok {
+		return
+	}
+
+	alocacoes, err := c.Repo.GetByProfessorID(id, periodoLetivoID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alocacoes)
+}
+
+// GetAlocacoesByDisciplina retorna todas as alocações de uma disciplina específica
+func (c *AlocacaoController) GetAlocacoesByDisciplina(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	periodoLetivoID, ok := periodoLetivoDaConsulta(w, r)
+	if !ok {
+		return
+	}
+
+	alocacoes, err := c.Repo.GetByDisciplinaID(id, periodoLetivoID)
 	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -557,8 +2450,17 @@ func (c *AlocacaoController) GetAlocacoesByTurma(w http.ResponseWriter, r *http.
 		return
 	}
 
-	alocacoes, err := c.Repo.GetByTurmaID(id)
+	periodoLetivoID, ok := periodoLetivoDaConsulta(w, r)
+	if !ok {
+		return
+	}
+
+	alocacoes, err := c.Repo.GetByTurmaID(id, periodoLetivoID)
 	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -567,13 +2469,115 @@ func (c *AlocacaoController) GetAlocacoesByTurma(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(alocacoes)
 }
 
-// OrganizarAlocacoesAutomaticas organiza alocações automaticamente para um dia e horário específicos
+// BuscarSalasLivres busca as salas livres em um dia e horário, filtrando por capacidade mínima,
+// bloco e tipo. Ex.: /api/salas/livres?dia_semana=Quarta&horario_inicio=14:00&horario_fim=16:00&capacidade_minima=40&bloco=B
+func (c *AlocacaoController) BuscarSalasLivres(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("dia_semana") == "" || query.Get("horario_inicio") == "" || query.Get("horario_fim") == "" {
+		http.Error(w, "Os parâmetros dia_semana, horario_inicio e horario_fim são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	var filtro models.FiltroSalasLivres
+	var err error
+	var ok bool
+	if filtro.DiaSemana, err = models.ParseDiaSemana(query.Get("dia_semana")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filtro.HorarioInicio, err = models.ParseHorario(query.Get("horario_inicio")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filtro.HorarioFim, err = models.ParseHorario(query.Get("horario_fim")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if capacidade := query.Get("capacidade_minima"); capacidade != "" {
+		if filtro.CapacidadeMinima, err = strconv.Atoi(capacidade); err != nil {
+			http.Error(w, "capacidade_minima inválida", http.StatusBadRequest)
+			return
+		}
+	}
+	filtro.Bloco = query.Get("bloco")
+	filtro.Tipo = query.Get("tipo")
+	if filtro.PeriodoLetivoID, ok = periodoLetivoDaConsulta(w, r); !ok {
+		return
+	}
+
+	salas, err := c.Repo.BuscarSalasLivres(filtro)
+	if err != nil {
+		if errors.Is(err, repositories.ErrAlocacaoInvalida) || errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(salas)
+}
+
+// GetAgenda retorna as aulas de cada data entre data_inicio e data_fim, com os dias não letivos e as
+// exceções aplicados, filtrando por sala, professor e turma. Sem data_fim, retorna a semana que começa
+// em data_inicio. Ex.: /api/agenda?data_inicio=2025-03-10&data_fim=2025-03-14&sala_id=3
+func (c *AlocacaoController) GetAgenda(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("data_inicio") == "" {
+		http.Error(w, "O parâmetro data_inicio é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	var filtro models.FiltroAgenda
+	var err error
+	if filtro.DataInicio, err = models.ParseData(query.Get("data_inicio")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if dataFim := query.Get("data_fim"); dataFim != "" {
+		if filtro.DataFim, err = models.ParseData(dataFim); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ids := map[string]*int{"sala_id": &filtro.SalaID, "professor_id": &filtro.ProfessorID, "turma_id": &filtro.TurmaID}
+	for parametro, id := range ids {
+		if valor := query.Get(parametro); valor != "" {
+			if *id, err = strconv.Atoi(valor); err != nil || *id <= 0 {
+				http.Error(w, parametro+" inválido", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	aulas, err := c.Repo.GetAgenda(filtro)
+	if err != nil {
+		if errors.Is(err, repositories.ErrAgendaInvalida) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(aulas)
+}
+
+// OrganizarAlocacoesAutomaticas organiza alocações automaticamente para um dia e horário específicos.
+// Com "preview" apenas simula e retorna o plano com um token; com "token" aplica um plano simulado.
 func (c *AlocacaoController) OrganizarAlocacoesAutomaticas(w http.ResponseWriter, r *http.Request) {
 	// Estrutura para receber os dados da requisição
 	type AlocacaoAutomaticaRequest struct {
-		DiaSemana     string `json:"dia_semana"`
-		HorarioInicio string `json:"horario_inicio"`
-		HorarioFim    string `json:"horario_fim"`
+		PeriodoLetivoID int              `json:"periodo_letivo_id"`
+		DiaSemana       models.DiaSemana `json:"dia_semana"`
+		HorarioInicio   models.Horario   `json:"horario_inicio"`
+		HorarioFim      models.Horario   `json:"horario_fim"`
+		Preview         bool             `json:"preview"`
+		Token           string           `json:"token"`
+		Transacional    bool             `json:"transacional"`
 	}
 
 	// Decodificar o corpo da requisição
@@ -584,21 +2588,160 @@ func (c *AlocacaoController) OrganizarAlocacoesAutomaticas(w http.ResponseWriter
 		return
 	}
 
+	// Aplicar um plano simulado anteriormente
+	if req.Token != "" {
+		resultado, err := c.Repo.AplicarPlanoAlocacao(req.Token, req.Transacional)
+		if err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrPlanoNaoEncontrado):
+				http.Error(w, "Plano de alocação não encontrado", http.StatusNotFound)
+			case errors.Is(err, repositories.ErrPlanoJaAplicado):
+				http.Error(w, "Plano de alocação já foi aplicado", http.StatusConflict)
+			default:
+				http.Error(w, "Erro ao aplicar plano de alocação: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		respondResultadoLote(w, resultado)
+		return
+	}
+
 	// Validar os dados recebidos
-	if req.DiaSemana == "" || req.HorarioInicio == "" || req.HorarioFim == "" {
+	if req.DiaSemana == "" {
 		http.Error(w, "Os campos dia_semana, horario_inicio e horario_fim são obrigatórios", http.StatusBadRequest)
 		return
 	}
+	if err := models.ValidarHorarioAula(req.HorarioInicio, req.HorarioFim); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Apenas simular as alocações, sem gravá-las
+	if req.Preview {
+		plano, err := c.Repo.SimularAlocacoesAutomaticas(req.PeriodoLetivoID, req.DiaSemana, req.HorarioInicio, req.HorarioFim)
+		if err != nil {
+			if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Erro ao simular alocações automaticamente: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(plano)
+		return
+	}
 
 	// Chamar o método do repositório para organizar as alocações automaticamente
-	alocacoes, err := c.Repo.OrganizarAlocacoesAutomaticas(req.DiaSemana, req.HorarioInicio, req.HorarioFim)
+	resultado, err := c.Repo.OrganizarAlocacoesAutomaticas(req.PeriodoLetivoID, req.DiaSemana, req.HorarioInicio, req.HorarioFim, req.Transacional)
 	if err != nil {
+		if errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, "Erro ao organizar alocações automaticamente: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Retornar as alocações criadas
+	// Retornar as alocações criadas e os erros de cada item
+	respondResultadoLote(w, resultado)
+}
+
+// respondErroAlocacao responde com 400 para dia, horário ou período letivo inválidos, com 409 e os detalhes de cada
+// conflito quando a alocação colide com outras já existentes, com 422 e as violações quando alguma
+// regra de alocação não é atendida e com 500 para os demais erros
+func respondErroAlocacao(w http.ResponseWriter, err error) {
+	if errors.Is(err, repositories.ErrAlocacaoInvalida) || errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var conflito *repositories.ConflitoError
+	if errors.As(err, &conflito) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]any{
+			"erro":      conflito.Error(),
+			"conflitos": conflito.Conflitos,
+		})
+		return
+	}
+
+	var validacao *repositories.ValidacaoError
+	if errors.As(err, &validacao) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]any{
+			"erro":      validacao.Error(),
+			"violacoes": validacao.Violacoes,
+		})
+		return
+	}
+
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// respondResultadoLote retorna o resultado de uma gravação em lote. Se nenhuma alocação foi
+// gravada, responde 422 com os erros de cada item; caso contrário, 201.
+func respondResultadoLote(w http.ResponseWriter, resultado models.ResultadoAlocacaoAutomatica) {
+	status := http.StatusCreated
+	if len(resultado.Alocacoes) == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(alocacoes)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resultado)
+}
+
+// GerarGradeSemanal monta uma grade semanal completa a partir das janelas de aula e da carga horária
+// semanal de cada turma. Com "preview" apenas simula e retorna o plano com um token.
+func (c *AlocacaoController) GerarGradeSemanal(w http.ResponseWriter, r *http.Request) {
+	// Estrutura para receber os dados da requisição
+	type GradeSemanalRequest struct {
+		PeriodoLetivoID int                        `json:"periodo_letivo_id"`
+		Horarios        []models.HorarioAula       `json:"horarios"`
+		Cargas          []models.CargaHorariaTurma `json:"cargas"`
+		Preview         bool                       `json:"preview"`
+		Transacional    bool                       `json:"transacional"`
+	}
+
+	// Decodificar o corpo da requisição
+	var req GradeSemanalRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Erro ao decodificar o corpo da requisição: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Apenas simular a grade, sem gravá-la
+	if req.Preview {
+		plano, err := c.Repo.SimularGradeSemanal(req.PeriodoLetivoID, req.Horarios, req.Cargas)
+		if err != nil {
+			if errors.Is(err, repositories.ErrGradeInvalida) || errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "Erro ao simular grade semanal: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(plano)
+		return
+	}
+
+	resultado, err := c.Repo.GerarGradeSemanal(req.PeriodoLetivoID, req.Horarios, req.Cargas, req.Transacional)
+	if err != nil {
+		if errors.Is(err, repositories.ErrGradeInvalida) || errors.Is(err, repositories.ErrPeriodoLetivoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Erro ao gerar grade semanal: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	respondResultadoLote(w, resultado)
 }
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// formatoData é o formato das datas na API e no banco
+const formatoData = "2006-01-02"
+
+// Data representa uma data do calendário, sem horário.
+// É serializada em JSON e gravada no banco no formato AAAA-MM-DD.
+type Data struct {
+	time.Time
+}
+
+// ParseData converte uma data informada como "2025-03-17" ou "17/03/2025"
+func ParseData(valor string) (Data, error) {
+	texto := strings.TrimSpace(valor)
+	for _, formato := range []string{formatoData, "02/01/2006"} {
+		if t, err := time.Parse(formato, texto); err == nil {
+			return Data{t}, nil
+		}
+	}
+	return Data{}, fmt.Errorf("data inválida %q: use o formato AAAA-MM-DD", valor)
+}
+
+// NovaData cria uma data a partir de ano, mês e dia
+func NovaData(ano int, mes time.Month, dia int) Data {
+	return Data{time.Date(ano, mes, dia, 0, 0, 0, 0, time.UTC)}
+}
+
+// Hoje retorna a data atual
+func Hoje() Data {
+	agora := time.Now()
+	return NovaData(agora.Year(), agora.Month(), agora.Day())
+}
+
+// String retorna a data no formato AAAA-MM-DD
+func (d Data) String() string {
+	return d.Format(formatoData)
+}
+
+// AdicionarDias retorna a data deslocada em n dias
+func (d Data) AdicionarDias(n int) Data {
+	return Data{d.AddDate(0, 0, n)}
+}
+
+// DiaSemana retorna o dia da semana da data
+func (d Data) DiaSemana() DiaSemana {
+	// time.Weekday começa no domingo; DiasSemana começa na segunda
+	return DiasSemana[(int(d.Weekday())+6)%7]
+}
+
+// MarshalJSON serializa a data no formato AAAA-MM-DD
+func (d Data) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON lê uma data informada como texto
+func (d *Data) UnmarshalJSON(data []byte) error {
+	var texto string
+	if err := json.Unmarshal(data, &texto); err != nil {
+		return fmt.Errorf("data deve ser um texto no formato AAAA-MM-DD")
+	}
+
+	valor, err := ParseData(texto)
+	if err != nil {
+		return err
+	}
+
+	*d = valor
+	return nil
+}
+
+// Scan lê uma data de uma coluna DATE
+func (d *Data) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*d = NovaData(v.Year(), v.Month(), v.Day())
+		return nil
+	case []byte:
+		return d.scanTexto(string(v))
+	case string:
+		return d.scanTexto(v)
+	}
+	return fmt.Errorf("não é possível converter %T em data", src)
+}
+
+func (d *Data) scanTexto(texto string) error {
+	valor, err := ParseData(texto)
+	if err != nil {
+		return err
+	}
+	*d = valor
+	return nil
+}
+
+// Value grava a data no formato AAAA-MM-DD
+func (d Data) Value() (driver.Value, error) {
+	return d.String(), nil
+}
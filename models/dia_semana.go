@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DiaSemana representa um dia da semana na sua grafia canônica
+type DiaSemana string
+
+// Dias da semana na grafia canônica, usada nas respostas da API e gravada no banco
+const (
+	Segunda DiaSemana = "Segunda"
+	Terca   DiaSemana = "Terça"
+	Quarta  DiaSemana = "Quarta"
+	Quinta  DiaSemana = "Quinta"
+	Sexta   DiaSemana = "Sexta"
+	Sabado  DiaSemana = "Sábado"
+	Domingo DiaSemana = "Domingo"
+)
+
+// DiasSemana lista os dias da semana em ordem, começando pela segunda-feira
+var DiasSemana = []DiaSemana{Segunda, Terca, Quarta, Quinta, Sexta, Sabado, Domingo}
+
+// grafiasDiaSemana associa as grafias aceitas na entrada, já sem acentos e em minúsculas, ao dia canônico
+var grafiasDiaSemana = map[string]DiaSemana{
+	"segunda": Segunda, "seg": Segunda, "2a": Segunda, "monday": Segunda, "mon": Segunda,
+	"terca": Terca, "ter": Terca, "3a": Terca, "tuesday": Terca, "tue": Terca,
+	"quarta": Quarta, "qua": Quarta, "4a": Quarta, "wednesday": Quarta, "wed": Quarta,
+	"quinta": Quinta, "qui": Quinta, "5a": Quinta, "thursday": Quinta, "thu": Quinta,
+	"sexta": Sexta, "sex": Sexta, "6a": Sexta, "friday": Sexta, "fri": Sexta,
+	"sabado": Sabado, "sab": Sabado, "saturday": Sabado, "sat": Sabado,
+	"domingo": Domingo, "dom": Domingo, "sunday": Domingo, "sun": Domingo,
+}
+
+var simplificadorDiaSemana = strings.NewReplacer("ç", "c", "á", "a", "Ç", "c", "Á", "a", "ª", "a", ".", "")
+
+// ParseDiaSemana converte grafias comuns em português e inglês ("segunda-feira", "Segunda",
+// "seg", "terça", "Monday", ...) no dia da semana canônico
+func ParseDiaSemana(valor string) (DiaSemana, error) {
+	texto := strings.ToLower(simplificadorDiaSemana.Replace(strings.TrimSpace(valor)))
+	texto = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(texto, "feira"), "-"))
+
+	dia, ok := grafiasDiaSemana[texto]
+	if !ok {
+		return "", fmt.Errorf("dia da semana inválido %q", valor)
+	}
+	return dia, nil
+}
+
+// Numero retorna a posição do dia na semana, de 1 (segunda) a 7 (domingo)
+func (d DiaSemana) Numero() int {
+	for i, dia := range DiasSemana {
+		if dia == d {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// UnmarshalJSON lê e normaliza um dia da semana informado em qualquer grafia aceita
+func (d *DiaSemana) UnmarshalJSON(data []byte) error {
+	var texto string
+	if err := json.Unmarshal(data, &texto); err != nil {
+		return fmt.Errorf("dia da semana deve ser um texto")
+	}
+
+	dia, err := ParseDiaSemana(texto)
+	if err != nil {
+		return err
+	}
+
+	*d = dia
+	return nil
+}
+
+// Scan lê um dia da semana do banco, normalizando grafias antigas
+func (d *DiaSemana) Scan(src any) error {
+	var texto string
+	switch v := src.(type) {
+	case []byte:
+		texto = string(v)
+	case string:
+		texto = v
+	default:
+		return fmt.Errorf("não é possível converter %T em dia da semana", src)
+	}
+
+	dia, err := ParseDiaSemana(texto)
+	if err != nil {
+		return err
+	}
+
+	*d = dia
+	return nil
+}
+
+// Value grava o dia da semana na grafia canônica
+func (d DiaSemana) Value() (driver.Value, error) {
+	return string(d), nil
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Horario representa um horário do dia, em minutos desde a meia-noite.
+// É serializado em JSON e gravado no banco no formato HH:MM.
+type Horario int
+
+// ParseHorario converte um horário informado como "19:00", "9:00", "09:00:00", "9h" ou "9h30"
+func ParseHorario(valor string) (Horario, error) {
+	texto := strings.ToLower(strings.TrimSpace(valor))
+	texto = strings.TrimSuffix(strings.Replace(texto, "h", ":", 1), ":")
+
+	partes := strings.Split(texto, ":")
+	if texto == "" || len(partes) > 3 {
+		return 0, fmt.Errorf("horário inválido %q: use o formato HH:MM", valor)
+	}
+
+	hora, err := strconv.Atoi(partes[0])
+	if err != nil || hora < 0 || hora > 23 {
+		return 0, fmt.Errorf("horário inválido %q: use o formato HH:MM", valor)
+	}
+
+	minuto := 0
+	if len(partes) > 1 {
+		minuto, err = strconv.Atoi(partes[1])
+		if err != nil || len(partes[1]) != 2 || minuto < 0 || minuto > 59 {
+			return 0, fmt.Errorf("horário inválido %q: use o formato HH:MM", valor)
+		}
+	}
+
+	if len(partes) > 2 {
+		segundo, err := strconv.Atoi(partes[2])
+		if err != nil || segundo != 0 {
+			return 0, fmt.Errorf("horário inválido %q: use o formato HH:MM", valor)
+		}
+	}
+
+	return Horario(hora*60 + minuto), nil
+}
+
+// String retorna o horário no formato HH:MM
+func (h Horario) String() string {
+	return fmt.Sprintf("%02d:%02d", int(h)/60, int(h)%60)
+}
+
+// Minutos retorna o horário em minutos desde a meia-noite
+func (h Horario) Minutos() int {
+	return int(h)
+}
+
+// MarshalJSON serializa o horário no formato HH:MM
+func (h Horario) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.String())
+}
+
+// UnmarshalJSON lê e normaliza um horário informado como texto
+func (h *Horario) UnmarshalJSON(data []byte) error {
+	var texto string
+	if err := json.Unmarshal(data, &texto); err != nil {
+		return fmt.Errorf("horário deve ser um texto no formato HH:MM")
+	}
+
+	horario, err := ParseHorario(texto)
+	if err != nil {
+		return err
+	}
+
+	*h = horario
+	return nil
+}
+
+// Scan lê um horário de uma coluna TIME (ou de texto, em bancos ainda não migrados)
+func (h *Horario) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*h = Horario(v.Hour()*60 + v.Minute())
+		return nil
+	case []byte:
+		return h.scanTexto(string(v))
+	case string:
+		return h.scanTexto(v)
+	}
+	return fmt.Errorf("não é possível converter %T em horário", src)
+}
+
+func (h *Horario) scanTexto(texto string) error {
+	horario, err := ParseHorario(texto)
+	if err != nil {
+		return err
+	}
+	*h = horario
+	return nil
+}
+
+// Value grava o horário no formato HH:MM
+func (h Horario) Value() (driver.Value, error) {
+	return h.String(), nil
+}
+
+// ValidarIntervalo verifica se o horário de fim é posterior ao de início
+func ValidarIntervalo(inicio, fim Horario) error {
+	if fim <= inicio {
+		return fmt.Errorf("horario_fim (%s) deve ser posterior a horario_inicio (%s)", fim, inicio)
+	}
+	return nil
+}
+
+// ValidarHorarioAula verifica se os horários de uma aula foram informados e formam um intervalo
+// válido. O horário zero é o valor de um campo ausente, então nenhuma aula começa à meia-noite.
+func ValidarHorarioAula(inicio, fim Horario) error {
+	if inicio == 0 {
+		return fmt.Errorf("horario_inicio é obrigatório")
+	}
+	if fim == 0 {
+		return fmt.Errorf("horario_fim é obrigatório")
+	}
+	return ValidarIntervalo(inicio, fim)
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 )
 
 // Professor representa um professor no sistema
@@ -13,6 +15,15 @@ type Professor struct {
 	Email      string `json:"email"`
 	Formacao   string `json:"formacao"`
 	Disciplina string `json:"disciplina"`
+
+	// DisciplinaIDs lista as disciplinas cadastradas que o professor leciona
+	DisciplinaIDs []int `json:"disciplina_ids"`
+
+	// Limites de carga horária, em horas; zero indica que não há limite
+	MaxHorasSemanais     float64 `json:"max_horas_semanais"`
+	MaxHorasDiarias      float64 `json:"max_horas_diarias"`
+	MaxHorasConsecutivas float64 `json:"max_horas_consecutivas"`
+	DescansoMinimo       float64 `json:"descanso_minimo"` // Entre a última aula de um dia e a primeira do dia seguinte
 }
 
 // Sala representa uma sala de aula no sistema
@@ -22,6 +33,39 @@ type Sala struct {
 	Capacidade int    `json:"capacidade"`
 	Bloco      string `json:"bloco"`
 	Tipo       string `json:"tipo"` // Laboratório, Sala comum, etc.
+
+	// BlocoID indica o bloco cadastrado da sala; sem ele, vale o nome em bloco
+	BlocoID *int `json:"bloco_id,omitempty"`
+	// Equipamentos é o inventário de equipamentos e recursos de acessibilidade da sala
+	Equipamentos Equipamentos `json:"equipamentos"`
+}
+
+// Equipamentos descreve os equipamentos de uma sala ou os que uma turma ou disciplina exige
+type Equipamentos struct {
+	Computadores   int      `json:"computadores"`
+	Projetor       bool     `json:"projetor"`
+	Bancadas       int      `json:"bancadas"`       // Bancadas de laboratório
+	Acessibilidade []string `json:"acessibilidade"` // Rampa, elevador, carteira adaptada, etc.
+}
+
+// Campus representa um campus da instituição, que reúne vários blocos
+type Campus struct {
+	ID   int    `json:"id"`
+	Nome string `json:"nome"`
+}
+
+// Bloco representa um prédio de um campus, onde ficam as salas
+type Bloco struct {
+	ID       int    `json:"id"`
+	Nome     string `json:"nome"`
+	CampusID *int   `json:"campus_id,omitempty"`
+}
+
+// Deslocamento indica o tempo, em minutos, para ir de um bloco a outro. O tempo vale nos dois sentidos.
+type Deslocamento struct {
+	BlocoOrigemID  int `json:"bloco_origem_id"`
+	BlocoDestinoID int `json:"bloco_destino_id"`
+	Minutos        int `json:"minutos"`
 }
 
 // Turma representa uma turma no sistema
@@ -31,6 +75,70 @@ type Turma struct {
 	Curso       string `json:"curso"`
 	Periodo     string `json:"periodo"`
 	QuantAlunos int    `json:"quant_alunos"`
+	Disciplina  string `json:"disciplina"` // Disciplina que o professor alocado deve lecionar
+	TipoSala    string `json:"tipo_sala"`  // Tipo de sala exigido pela turma (vazio aceita qualquer tipo)
+
+	// TiposSalaAceitos lista outros tipos de sala que também atendem a turma, além do tipo_sala
+	TiposSalaAceitos []string `json:"tipos_sala_aceitos"`
+	// TurnoID indica o turno da turma; as alocações precisam caber na janela de horário dele
+	TurnoID *int `json:"turno_id,omitempty"`
+	// DisciplinaID indica a disciplina cadastrada da turma; sem ele, vale o nome em disciplina
+	DisciplinaID *int `json:"disciplina_id,omitempty"`
+	// CursoID e Semestre indicam o curso cadastrado da turma e o semestre da grade curricular
+	// que ela cursa, de onde vem a carga horária semanal da turma
+	CursoID  *int `json:"curso_id,omitempty"`
+	Semestre int  `json:"semestre,omitempty"`
+	// EquipamentosExigidos são os equipamentos que a sala precisa ter para a turma, somados aos
+	// exigidos pela disciplina da aula
+	EquipamentosExigidos Equipamentos `json:"equipamentos_exigidos"`
+}
+
+// Curso representa um curso e a sua grade curricular
+type Curso struct {
+	ID        int    `json:"id"`
+	Nome      string `json:"nome"`
+	Semestres int    `json:"semestres"` // Duração do curso; zero indica que não foi informada
+
+	// GradeCurricular lista as disciplinas de cada semestre e a carga horária semanal de cada uma
+	GradeCurricular []DisciplinaCurso `json:"grade_curricular"`
+}
+
+// DisciplinaCurso é uma disciplina da grade curricular de um curso em um semestre
+type DisciplinaCurso struct {
+	DisciplinaID  int     `json:"disciplina_id"`
+	Disciplina    string  `json:"disciplina,omitempty"` // Nome da disciplina, preenchido na leitura
+	Semestre      int     `json:"semestre"`
+	HorasSemanais float64 `json:"horas_semanais"`
+}
+
+// Disciplina representa uma disciplina que pode ser lecionada por vários professores
+type Disciplina struct {
+	ID     int    `json:"id"`
+	Nome   string `json:"nome"`
+	Codigo string `json:"codigo,omitempty"`
+
+	// EquipamentosExigidos são os equipamentos que a sala precisa ter nas aulas da disciplina
+	EquipamentosExigidos Equipamentos `json:"equipamentos_exigidos"`
+}
+
+// Turno define a janela de horário de um turno de aulas, como Matutino ou Noturno
+type Turno struct {
+	ID            int     `json:"id"`
+	Nome          string  `json:"nome"`
+	HorarioInicio Horario `json:"horario_inicio"`
+	HorarioFim    Horario `json:"horario_fim"`
+}
+
+// Recorrencia descreve em quais semanas do período letivo uma alocação acontece. As semanas são
+// contadas a partir de 1, de segunda a domingo, sendo a primeira a que contém o início do período.
+type Recorrencia struct {
+	// IntervaloSemanas é o número de semanas entre duas aulas, contado a partir da primeira aula; 2 para semanas alternadas
+	IntervaloSemanas int `json:"intervalo_semanas,omitempty"`
+	// DataInicio e DataFim limitam as aulas a uma parte do período letivo, como as oito primeiras semanas
+	DataInicio *Data `json:"data_inicio,omitempty"`
+	DataFim    *Data `json:"data_fim,omitempty"`
+	// Semanas lista explicitamente as semanas do período letivo com aula, como as semanas de laboratório
+	Semanas []int `json:"semanas,omitempty"`
 }
 
 // Alocacao representa a associação entre professor, sala e turma
@@ -39,12 +147,272 @@ type Alocacao struct {
 	ProfessorID   int       `json:"professor_id"`
 	SalaID        int       `json:"sala_id"`
 	TurmaID       int       `json:"turma_id"`
-	DiaSemana     string    `json:"dia_semana"`
-	HorarioInicio string    `json:"horario_inicio"`
-	HorarioFim    string    `json:"horario_fim"`
-	Professor     Professor `json:"professor,omitempty"`
-	Sala          Sala      `json:"sala,omitempty"`
-	Turma         Turma     `json:"turma,omitempty"`
+	DiaSemana     DiaSemana `json:"dia_semana"`
+	HorarioInicio Horario   `json:"horario_inicio"`
+	HorarioFim    Horario   `json:"horario_fim"`
+
+	// PeriodoLetivoID indica o período letivo da alocação; se omitido ao criar, vale o período letivo atual
+	PeriodoLetivoID int `json:"periodo_letivo_id"`
+	// Recorrencia restringe as semanas do período letivo em que a aula acontece; se omitida, ela acontece toda semana
+	Recorrencia *Recorrencia `json:"recorrencia,omitempty"`
+	// IgnorarCapacidade permite alocar uma turma maior que a capacidade da sala; fica registrado na alocação
+	IgnorarCapacidade bool `json:"ignorar_capacidade"`
+	// LugaresFaltantes é calculado na leitura: quantos alunos da turma excedem a capacidade da sala
+	LugaresFaltantes int `json:"lugares_faltantes,omitempty"`
+	// BloqueioSalaID indica o bloqueio da sala em que a alocação caiu depois de gravada
+	BloqueioSalaID *int `json:"bloqueio_sala_id,omitempty"`
+	// DisciplinaID indica a disciplina lecionada na aula; se omitido, vale a disciplina da turma
+	DisciplinaID *int `json:"disciplina_id,omitempty"`
+	// Avisos lista situações que não impedem a alocação mas merecem atenção, como o deslocamento
+	// entre blocos distantes; é preenchido ao criar ou atualizar a alocação
+	Avisos []Violacao `json:"avisos,omitempty"`
+
+	Professor  Professor   `json:"professor,omitempty"`
+	Sala       Sala        `json:"sala,omitempty"`
+	Turma      Turma       `json:"turma,omitempty"`
+	Disciplina *Disciplina `json:"disciplina,omitempty"`
+}
+
+// Tipos de janela de disponibilidade de professor
+const (
+	Disponivel   = "disponivel"   // Janela em que o professor pode lecionar
+	Indisponivel = "indisponivel" // Horário em que o professor não pode lecionar
+)
+
+// DisponibilidadeProfessor representa uma janela semanal em que o professor está disponível
+// ou indisponível. Se o professor tiver janelas disponíveis, só pode ser alocado dentro delas.
+type DisponibilidadeProfessor struct {
+	ID            int       `json:"id"`
+	ProfessorID   int       `json:"professor_id"`
+	DiaSemana     DiaSemana `json:"dia_semana"`
+	HorarioInicio Horario   `json:"horario_inicio"`
+	HorarioFim    Horario   `json:"horario_fim"`
+	Tipo          string    `json:"tipo"` // disponivel ou indisponivel
+	Motivo        string    `json:"motivo,omitempty"`
+}
+
+// PreferenciaProfessor reúne as preferências de dias, turnos, blocos e salas de um professor.
+// Diferente da disponibilidade, não impede alocações: a alocação automática procura atendê-las.
+type PreferenciaProfessor struct {
+	ProfessorID int         `json:"professor_id"`
+	DiasSemana  []DiaSemana `json:"dias_semana"`
+	Turnos      []string    `json:"turnos"` // Nomes de turnos cadastrados, como Matutino ou Noturno
+	Blocos      []string    `json:"blocos"`
+	SalaIDs     []int       `json:"sala_ids"`
+}
+
+// SatisfacaoProfessor resume o quanto as alocações de uma execução atendem às preferências de um professor
+type SatisfacaoProfessor struct {
+	ProfessorID           int     `json:"professor_id"`
+	Nome                  string  `json:"nome"`
+	Aulas                 int     `json:"aulas"`
+	PreferenciasAvaliadas int     `json:"preferencias_avaliadas"`
+	PreferenciasAtendidas int     `json:"preferencias_atendidas"`
+	Pontuacao             float64 `json:"pontuacao"` // Percentual de preferências atendidas; 100 se o professor não tem preferências
+}
+
+// PeriodoLetivo representa um período letivo, como um semestre. Cada período tem a sua própria grade
+// de alocações, e os períodos não podem se sobrepor.
+type PeriodoLetivo struct {
+	ID         int    `json:"id"`
+	Nome       string `json:"nome"`
+	DataInicio Data   `json:"data_inicio"`
+	DataFim    Data   `json:"data_fim"`
+	Atual      bool   `json:"atual"` // Calculado na leitura: indica o período letivo usado quando nenhum é informado
+}
+
+// BloqueioSala representa um período em que a sala está fora de uso, como reforma ou provas.
+// O bloqueio pode ser semanal (dia_semana, com ou sem horário) ou por período de datas (data_inicio
+// e data_fim, com ou sem horário); sem horário, o dia inteiro fica bloqueado.
+type BloqueioSala struct {
+	ID            int       `json:"id"`
+	SalaID        int       `json:"sala_id"`
+	DiaSemana     DiaSemana `json:"dia_semana,omitempty"`
+	HorarioInicio *Horario  `json:"horario_inicio,omitempty"`
+	HorarioFim    *Horario  `json:"horario_fim,omitempty"`
+	DataInicio    *Data     `json:"data_inicio,omitempty"`
+	DataFim       *Data     `json:"data_fim,omitempty"`
+	Motivo        string    `json:"motivo"`
+
+	// AlocacoesAfetadas lista as alocações já existentes que caem no bloqueio
+	AlocacoesAfetadas []int `json:"alocacoes_afetadas,omitempty"`
+}
+
+// Tipos de dia não letivo
+const (
+	DiaNaoLetivoFeriado = "feriado"
+	DiaNaoLetivoSemAula = "sem_aula"
+)
+
+// DiaNaoLetivo representa um feriado ou outro dia sem aulas, como um recesso ou uma semana de
+// provas. Nenhuma aula das alocações semanais acontece nesses dias.
+type DiaNaoLetivo struct {
+	ID        int    `json:"id"`
+	Data      Data   `json:"data"`
+	Descricao string `json:"descricao"`
+	Tipo      string `json:"tipo"` // feriado ou sem_aula; se omitido, feriado
+}
+
+// Tipos de exceção de uma alocação em uma data
+const (
+	ExcecaoCancelada = "cancelada"
+	ExcecaoTrocaSala = "troca_sala"
+	ExcecaoReposicao = "reposicao"
+)
+
+// ExcecaoAlocacao altera uma única aula de uma alocação semanal: a aula da data é cancelada,
+// acontece em outra sala ou é reposta em outra data.
+type ExcecaoAlocacao struct {
+	ID         int    `json:"id"`
+	AlocacaoID int    `json:"alocacao_id"`
+	Data       Data   `json:"data"` // Data da aula afetada; deve cair no dia da semana e no período letivo da alocação
+	Tipo       string `json:"tipo"` // cancelada, troca_sala ou reposicao
+
+	// SalaID é a sala da aula na troca de sala; na reposição é opcional e, se omitida, vale a sala da alocação
+	SalaID *int `json:"sala_id,omitempty"`
+	// NovaData, HorarioInicio e HorarioFim indicam quando a aula é reposta; sem horário, vale o da alocação
+	NovaData      *Data    `json:"nova_data,omitempty"`
+	HorarioInicio *Horario `json:"horario_inicio,omitempty"`
+	HorarioFim    *Horario `json:"horario_fim,omitempty"`
+	Motivo        string   `json:"motivo,omitempty"`
+}
+
+// Situações de uma aula na agenda
+const (
+	AulaNormal    = "normal"
+	AulaCancelada = "cancelada"
+	AulaNaoLetivo = "nao_letivo"
+	AulaTrocaSala = "troca_sala"
+	AulaRemarcada = "remarcada"
+	AulaReposicao = "reposicao"
+)
+
+// FiltroAgenda define o intervalo de datas e, opcionalmente, a sala, o professor ou a turma de uma consulta da agenda
+type FiltroAgenda struct {
+	DataInicio  Data `json:"data_inicio"`
+	DataFim     Data `json:"data_fim"`
+	SalaID      int  `json:"sala_id,omitempty"`
+	ProfessorID int  `json:"professor_id,omitempty"`
+	TurmaID     int  `json:"turma_id,omitempty"`
+}
+
+// AulaAgenda é uma aula em uma data, obtida das alocações semanais com os dias não letivos e as
+// exceções já aplicados. Aulas canceladas, em dia não letivo ou remarcadas para outra data também
+// aparecem, com a situação correspondente, mas não acontecem.
+type AulaAgenda struct {
+	Data          Data      `json:"data"`
+	DiaSemana     DiaSemana `json:"dia_semana"`
+	HorarioInicio Horario   `json:"horario_inicio"`
+	HorarioFim    Horario   `json:"horario_fim"`
+	Situacao      string    `json:"situacao"` // normal, cancelada, nao_letivo, troca_sala, remarcada ou reposicao
+	Motivo        string    `json:"motivo,omitempty"`
+	AlocacaoID    int       `json:"alocacao_id"`
+	ExcecaoID     *int      `json:"excecao_id,omitempty"`
+
+	ProfessorID  int    `json:"professor_id"`
+	Professor    string `json:"professor"`
+	SalaID       int    `json:"sala_id"`
+	Sala         string `json:"sala"`
+	TurmaID      int    `json:"turma_id"`
+	Turma        string `json:"turma"`
+	DisciplinaID *int   `json:"disciplina_id,omitempty"`
+	Disciplina   string `json:"disciplina,omitempty"`
+}
+
+// Acontece indica se a aula é dada na data, ou seja, se não foi cancelada, remarcada nem cai em dia não letivo
+func (a AulaAgenda) Acontece() bool {
+	return a.Situacao == AulaNormal || a.Situacao == AulaTrocaSala || a.Situacao == AulaReposicao
+}
+
+// FiltroSalasLivres define a busca de salas livres em um dia e horário
+type FiltroSalasLivres struct {
+	DiaSemana        DiaSemana `json:"dia_semana"`
+	HorarioInicio    Horario   `json:"horario_inicio"`
+	HorarioFim       Horario   `json:"horario_fim"`
+	CapacidadeMinima int       `json:"capacidade_minima,omitempty"`
+	Bloco            string    `json:"bloco,omitempty"`
+	Tipo             string    `json:"tipo,omitempty"`
+	PeriodoLetivoID  int       `json:"periodo_letivo_id,omitempty"` // Se omitido, vale o período letivo atual
+}
+
+// SalaLivre é uma sala encontrada pela busca de salas livres
+type SalaLivre struct {
+	Sala
+	LugaresOciosos int `json:"lugares_ociosos"` // Lugares que sobram em relação à capacidade mínima pedida
+}
+
+// Violacao descreve uma regra de alocação que não foi atendida
+type Violacao struct {
+	Regra    string         `json:"regra"`
+	Mensagem string         `json:"mensagem"`
+	Detalhes map[string]any `json:"detalhes,omitempty"`
+}
+
+// Conflito descreve uma alocação existente que já ocupa o mesmo recurso no mesmo dia e horário.
+// Quando o conflito é detectado pelo banco sem que a alocação concorrente possa ser identificada,
+// apenas o recurso é informado.
+type Conflito struct {
+	Recurso       string    `json:"recurso"` // sala, professor ou turma
+	RecursoID     int       `json:"recurso_id"`
+	AlocacaoID    int       `json:"alocacao_id,omitempty"`
+	ExcecaoID     *int      `json:"excecao_id,omitempty"` // Preenchido quando a aula em conflito é uma troca de sala ou reposição
+	Data          *Data     `json:"data,omitempty"`       // Preenchida nos conflitos de uma aula em uma data específica
+	DiaSemana     DiaSemana `json:"dia_semana,omitempty"`
+	HorarioInicio *Horario  `json:"horario_inicio,omitempty"`
+	HorarioFim    *Horario  `json:"horario_fim,omitempty"`
+}
+
+// RecursoNaoAlocado descreve um professor, sala ou turma que ficou de fora da alocação automática
+type RecursoNaoAlocado struct {
+	Tipo   string `json:"tipo"` // professor, sala ou turma
+	ID     int    `json:"id"`
+	Nome   string `json:"nome"`
+	Motivo string `json:"motivo"`
+}
+
+// PlanoAlocacao representa uma simulação de alocação automática que pode ser aplicada depois pelo token
+type PlanoAlocacao struct {
+	Token           string              `json:"token"`
+	PeriodoLetivoID int                 `json:"periodo_letivo_id"`
+	DiaSemana       DiaSemana           `json:"dia_semana"`
+	HorarioInicio   Horario             `json:"horario_inicio"`
+	HorarioFim      Horario             `json:"horario_fim"`
+	Alocacoes       []Alocacao          `json:"alocacoes"`
+	NaoAlocados     []RecursoNaoAlocado `json:"nao_alocados"`
+	CriadoEm        time.Time           `json:"criado_em"`
+
+	Satisfacao []SatisfacaoProfessor `json:"satisfacao_professores"`
+}
+
+// ErroAlocacao descreve uma alocação de um lote que não pôde ser gravada
+type ErroAlocacao struct {
+	Alocacao Alocacao `json:"alocacao"`
+	Erro     string   `json:"erro"`
+}
+
+// ResultadoAlocacaoAutomatica reúne as alocações gravadas em lote e os erros de cada item que falhou
+type ResultadoAlocacaoAutomatica struct {
+	Alocacoes    []Alocacao     `json:"alocacoes"`
+	Erros        []ErroAlocacao `json:"erros"`
+	Transacional bool           `json:"transacional"`
+	Revertido    bool           `json:"revertido"` // No modo transacional, indica que nada foi gravado
+
+	NaoAlocados []RecursoNaoAlocado   `json:"nao_alocados,omitempty"`
+	Satisfacao  []SatisfacaoProfessor `json:"satisfacao_professores"`
+}
+
+// HorarioAula representa uma janela de aula disponível na semana
+type HorarioAula struct {
+	DiaSemana     DiaSemana `json:"dia_semana"`
+	HorarioInicio Horario   `json:"horario_inicio"`
+	HorarioFim    Horario   `json:"horario_fim"`
+}
+
+// CargaHorariaTurma indica quantas horas semanais de aula uma turma precisa, no total ou em uma disciplina
+type CargaHorariaTurma struct {
+	TurmaID       int     `json:"turma_id"`
+	DisciplinaID  *int    `json:"disciplina_id,omitempty"`
+	HorasSemanais float64 `json:"horas_semanais"`
 }
 
 // MigrateTables cria as tabelas no banco de dados se não existirem
@@ -56,7 +424,11 @@ func MigrateTables(db *sql.DB) {
 		nome VARCHAR(100) NOT NULL,
 		email VARCHAR(100) UNIQUE NOT NULL,
 		formacao VARCHAR(100),
-		disciplina VARCHAR(100) NOT NULL
+		disciplina VARCHAR(100) NOT NULL,
+		max_horas_semanais NUMERIC(5,2) NOT NULL DEFAULT 0,
+		max_horas_diarias NUMERIC(5,2) NOT NULL DEFAULT 0,
+		max_horas_consecutivas NUMERIC(5,2) NOT NULL DEFAULT 0,
+		descanso_minimo NUMERIC(5,2) NOT NULL DEFAULT 0
 	);
 	`
 	_, err := db.Exec(createProfessorTable)
@@ -64,6 +436,89 @@ func MigrateTables(db *sql.DB) {
 		log.Fatalf("Erro ao criar tabela de professores: %v", err)
 	}
 
+	// Adicionar colunas de limites de carga horária em tabelas de professores já existentes
+	alterProfessorTable := `
+	ALTER TABLE professores ADD COLUMN IF NOT EXISTS max_horas_semanais NUMERIC(5,2) NOT NULL DEFAULT 0;
+	ALTER TABLE professores ADD COLUMN IF NOT EXISTS max_horas_diarias NUMERIC(5,2) NOT NULL DEFAULT 0;
+	ALTER TABLE professores ADD COLUMN IF NOT EXISTS max_horas_consecutivas NUMERIC(5,2) NOT NULL DEFAULT 0;
+	ALTER TABLE professores ADD COLUMN IF NOT EXISTS descanso_minimo NUMERIC(5,2) NOT NULL DEFAULT 0;
+	`
+	_, err = db.Exec(alterProfessorTable)
+	if err != nil {
+		log.Fatalf("Erro ao atualizar tabela de professores: %v", err)
+	}
+
+	// Criar tabela de disciplinas e a associação entre professores e disciplinas
+	createDisciplinaTable := `
+	CREATE TABLE IF NOT EXISTS disciplinas (
+		id SERIAL PRIMARY KEY,
+		nome VARCHAR(100) NOT NULL,
+		codigo VARCHAR(20) NOT NULL DEFAULT ''
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS disciplinas_nome_unico ON disciplinas (LOWER(nome));
+	CREATE TABLE IF NOT EXISTS professor_disciplinas (
+		professor_id INT NOT NULL REFERENCES professores(id) ON DELETE CASCADE,
+		disciplina_id INT NOT NULL REFERENCES disciplinas(id) ON DELETE CASCADE,
+		PRIMARY KEY (professor_id, disciplina_id)
+	);
+	ALTER TABLE disciplinas ADD COLUMN IF NOT EXISTS exige_computadores INT NOT NULL DEFAULT 0;
+	ALTER TABLE disciplinas ADD COLUMN IF NOT EXISTS exige_projetor BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE disciplinas ADD COLUMN IF NOT EXISTS exige_bancadas INT NOT NULL DEFAULT 0;
+	ALTER TABLE disciplinas ADD COLUMN IF NOT EXISTS exige_acessibilidade TEXT[] NOT NULL DEFAULT '{}';
+	`
+	_, err = db.Exec(createDisciplinaTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de disciplinas: %v", err)
+	}
+
+	// Criar tabela de cursos e das grades curriculares
+	createCursoTable := `
+	CREATE TABLE IF NOT EXISTS cursos (
+		id SERIAL PRIMARY KEY,
+		nome VARCHAR(100) NOT NULL,
+		semestres INT NOT NULL DEFAULT 0 CHECK (semestres >= 0)
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS cursos_nome_unico ON cursos (LOWER(nome));
+	CREATE TABLE IF NOT EXISTS grade_curricular (
+		curso_id INT NOT NULL REFERENCES cursos(id) ON DELETE CASCADE,
+		disciplina_id INT NOT NULL REFERENCES disciplinas(id) ON DELETE CASCADE,
+		semestre INT NOT NULL CHECK (semestre > 0),
+		horas_semanais NUMERIC(5,2) NOT NULL CHECK (horas_semanais > 0),
+		PRIMARY KEY (curso_id, disciplina_id)
+	);
+	`
+	_, err = db.Exec(createCursoTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de cursos: %v", err)
+	}
+
+	// Criar tabelas de campi, blocos e tempos de deslocamento entre blocos. Os nomes de blocos são
+	// comparados sem o prefixo "Bloco", para que "B" e "Bloco B" sejam o mesmo bloco.
+	createBlocoTable := `
+	CREATE TABLE IF NOT EXISTS campi (
+		id SERIAL PRIMARY KEY,
+		nome VARCHAR(100) NOT NULL
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS campi_nome_unico ON campi (LOWER(nome));
+	CREATE TABLE IF NOT EXISTS blocos (
+		id SERIAL PRIMARY KEY,
+		nome VARCHAR(50) NOT NULL,
+		campus_id INT REFERENCES campi(id) ON DELETE SET NULL
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS blocos_nome_unico ON blocos (REGEXP_REPLACE(LOWER(TRIM(nome)), '^bloco\s+', ''));
+	CREATE TABLE IF NOT EXISTS deslocamentos_blocos (
+		bloco_origem_id INT NOT NULL REFERENCES blocos(id) ON DELETE CASCADE,
+		bloco_destino_id INT NOT NULL REFERENCES blocos(id) ON DELETE CASCADE,
+		minutos INT NOT NULL CHECK (minutos >= 0),
+		PRIMARY KEY (bloco_origem_id, bloco_destino_id),
+		CONSTRAINT deslocamentos_blocos_ordem CHECK (bloco_origem_id < bloco_destino_id)
+	);
+	`
+	_, err = db.Exec(createBlocoTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabelas de blocos: %v", err)
+	}
+
 	// Criar tabela de salas
 	createSalaTable := `
 	CREATE TABLE IF NOT EXISTS salas (
@@ -71,14 +526,55 @@ func MigrateTables(db *sql.DB) {
 		numero VARCHAR(20) NOT NULL,
 		capacidade INT NOT NULL,
 		bloco VARCHAR(50),
-		tipo VARCHAR(50)
+		tipo VARCHAR(50),
+		bloco_id INT REFERENCES blocos(id) ON DELETE SET NULL
 	);
+	ALTER TABLE salas ADD COLUMN IF NOT EXISTS bloco_id INT REFERENCES blocos(id) ON DELETE SET NULL;
+	ALTER TABLE salas ADD COLUMN IF NOT EXISTS computadores INT NOT NULL DEFAULT 0;
+	ALTER TABLE salas ADD COLUMN IF NOT EXISTS projetor BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE salas ADD COLUMN IF NOT EXISTS bancadas INT NOT NULL DEFAULT 0;
+	ALTER TABLE salas ADD COLUMN IF NOT EXISTS acessibilidade TEXT[] NOT NULL DEFAULT '{}';
 	`
 	_, err = db.Exec(createSalaTable)
 	if err != nil {
 		log.Fatalf("Erro ao criar tabela de salas: %v", err)
 	}
 
+	// Cadastrar os blocos informados como texto nas salas e associá-los a elas
+	vincularBlocos := `
+	INSERT INTO blocos (nome)
+	SELECT DISTINCT TRIM(bloco) FROM salas WHERE TRIM(COALESCE(bloco, '')) <> ''
+	ON CONFLICT DO NOTHING;
+	UPDATE salas s SET bloco_id = b.id FROM blocos b
+	WHERE s.bloco_id IS NULL
+	AND REGEXP_REPLACE(LOWER(TRIM(s.bloco)), '^bloco\s+', '') = REGEXP_REPLACE(LOWER(TRIM(b.nome)), '^bloco\s+', '');
+	`
+	_, err = db.Exec(vincularBlocos)
+	if err != nil {
+		log.Fatalf("Erro ao associar blocos às salas: %v", err)
+	}
+
+	// Criar tabela de turnos, com os turnos padrão
+	createTurnoTable := `
+	CREATE TABLE IF NOT EXISTS turnos (
+		id SERIAL PRIMARY KEY,
+		nome VARCHAR(50) UNIQUE NOT NULL,
+		horario_inicio TIME NOT NULL,
+		horario_fim TIME NOT NULL,
+		CONSTRAINT turnos_horario_valido CHECK (horario_fim > horario_inicio)
+	);
+	INSERT INTO turnos (nome, horario_inicio, horario_fim) VALUES
+		('Matutino', '07:00', '12:00'),
+		('Vespertino', '12:00', '18:00'),
+		('Noturno', '18:00', '23:00'),
+		('Integral', '07:00', '18:00')
+	ON CONFLICT (nome) DO NOTHING;
+	`
+	_, err = db.Exec(createTurnoTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de turnos: %v", err)
+	}
+
 	// Criar tabela de turmas
 	createTurmaTable := `
 	CREATE TABLE IF NOT EXISTS turmas (
@@ -86,7 +582,14 @@ func MigrateTables(db *sql.DB) {
 		nome VARCHAR(100) NOT NULL,
 		curso VARCHAR(100) NOT NULL,
 		periodo VARCHAR(50),
-		quant_alunos INT
+		quant_alunos INT,
+		disciplina VARCHAR(100) NOT NULL DEFAULT '',
+		tipo_sala VARCHAR(50) NOT NULL DEFAULT '',
+		tipos_sala_aceitos TEXT[] NOT NULL DEFAULT '{}',
+		turno_id INT REFERENCES turnos(id) ON DELETE SET NULL,
+		disciplina_id INT REFERENCES disciplinas(id) ON DELETE SET NULL,
+		curso_id INT REFERENCES cursos(id) ON DELETE SET NULL,
+		semestre INT NOT NULL DEFAULT 0 CHECK (semestre >= 0)
 	);
 	`
 	_, err = db.Exec(createTurmaTable)
@@ -94,6 +597,101 @@ func MigrateTables(db *sql.DB) {
 		log.Fatalf("Erro ao criar tabela de turmas: %v", err)
 	}
 
+	// Adicionar colunas de requisitos em tabelas de turmas já existentes
+	alterTurmaTable := `
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS disciplina VARCHAR(100) NOT NULL DEFAULT '';
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS tipo_sala VARCHAR(50) NOT NULL DEFAULT '';
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS tipos_sala_aceitos TEXT[] NOT NULL DEFAULT '{}';
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS turno_id INT REFERENCES turnos(id) ON DELETE SET NULL;
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS disciplina_id INT REFERENCES disciplinas(id) ON DELETE SET NULL;
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS curso_id INT REFERENCES cursos(id) ON DELETE SET NULL;
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS semestre INT NOT NULL DEFAULT 0 CHECK (semestre >= 0);
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS exige_computadores INT NOT NULL DEFAULT 0;
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS exige_projetor BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS exige_bancadas INT NOT NULL DEFAULT 0;
+	ALTER TABLE turmas ADD COLUMN IF NOT EXISTS exige_acessibilidade TEXT[] NOT NULL DEFAULT '{}';
+	`
+	_, err = db.Exec(alterTurmaTable)
+	if err != nil {
+		log.Fatalf("Erro ao atualizar tabela de turmas: %v", err)
+	}
+
+	// Associar ao turno as turmas cujo período tem o nome de um turno, como "Noturno"
+	vincularTurnos := `
+	UPDATE turmas t SET turno_id = tu.id FROM turnos tu
+	WHERE t.turno_id IS NULL AND LOWER(TRIM(t.periodo)) = LOWER(tu.nome);
+	`
+	_, err = db.Exec(vincularTurnos)
+	if err != nil {
+		log.Fatalf("Erro ao associar turmas aos turnos: %v", err)
+	}
+
+	// Cadastrar as disciplinas informadas como texto em professores e turmas e associá-las a eles
+	vincularDisciplinas := `
+	INSERT INTO disciplinas (nome)
+	SELECT TRIM(disciplina) FROM professores WHERE TRIM(disciplina) <> ''
+	UNION
+	SELECT TRIM(disciplina) FROM turmas WHERE TRIM(disciplina) <> ''
+	ON CONFLICT DO NOTHING;
+	INSERT INTO professor_disciplinas (professor_id, disciplina_id)
+	SELECT p.id, d.id FROM professores p JOIN disciplinas d ON LOWER(TRIM(p.disciplina)) = LOWER(d.nome)
+	ON CONFLICT DO NOTHING;
+	UPDATE turmas t SET disciplina_id = d.id FROM disciplinas d
+	WHERE t.disciplina_id IS NULL AND LOWER(TRIM(t.disciplina)) = LOWER(d.nome);
+	`
+	_, err = db.Exec(vincularDisciplinas)
+	if err != nil {
+		log.Fatalf("Erro ao associar disciplinas a professores e turmas: %v", err)
+	}
+
+	// Cadastrar os cursos informados como texto nas turmas e associá-los a elas. O semestre é lido
+	// do período quando ele começa com um número, como "3º Semestre".
+	vincularCursos := `
+	INSERT INTO cursos (nome)
+	SELECT DISTINCT TRIM(curso) FROM turmas WHERE TRIM(curso) <> ''
+	ON CONFLICT DO NOTHING;
+	UPDATE turmas t SET curso_id = c.id FROM cursos c
+	WHERE t.curso_id IS NULL AND LOWER(TRIM(t.curso)) = LOWER(c.nome);
+	UPDATE turmas SET semestre = CAST(SUBSTRING(periodo FROM '^\s*(\d{1,2})') AS INT)
+	WHERE semestre = 0 AND periodo ~ '^\s*\d{1,2}';
+	`
+	_, err = db.Exec(vincularCursos)
+	if err != nil {
+		log.Fatalf("Erro ao associar cursos às turmas: %v", err)
+	}
+
+	// Criar tabela de períodos letivos. Os períodos não podem se sobrepor, para que o período atual
+	// seja sempre um só.
+	createPeriodoLetivoTable := `
+	CREATE TABLE IF NOT EXISTS periodos_letivos (
+		id SERIAL PRIMARY KEY,
+		nome VARCHAR(50) NOT NULL,
+		data_inicio DATE NOT NULL,
+		data_fim DATE NOT NULL,
+		CONSTRAINT periodos_letivos_data_valida CHECK (data_fim >= data_inicio),
+		CONSTRAINT periodos_letivos_sem_sobreposicao EXCLUDE USING gist (daterange(data_inicio, data_fim, '[]') WITH &&)
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS periodos_letivos_nome_unico ON periodos_letivos (LOWER(nome));
+	`
+	_, err = db.Exec(createPeriodoLetivoTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de períodos letivos: %v", err)
+	}
+
+	// Cadastrar o semestre corrente se não houver nenhum período letivo, para que as alocações
+	// tenham sempre um período letivo atual
+	ano, semestre := time.Now().Year(), 1
+	inicio, fim := NovaData(ano, time.January, 1), NovaData(ano, time.June, 30)
+	if time.Now().Month() > time.June {
+		semestre = 2
+		inicio, fim = NovaData(ano, time.July, 1), NovaData(ano, time.December, 31)
+	}
+	_, err = db.Exec(`INSERT INTO periodos_letivos (nome, data_inicio, data_fim)
+		SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT 1 FROM periodos_letivos)`, fmt.Sprintf("%d/%d", ano, semestre), inicio, fim)
+	if err != nil {
+		log.Fatalf("Erro ao cadastrar o período letivo inicial: %v", err)
+	}
+
 	// Criar tabela de alocações
 	createAlocacaoTable := `
 	CREATE TABLE IF NOT EXISTS alocacoes (
@@ -102,9 +700,12 @@ func MigrateTables(db *sql.DB) {
 		sala_id INT REFERENCES salas(id),
 		turma_id INT REFERENCES turmas(id),
 		dia_semana VARCHAR(20) NOT NULL,
-		horario_inicio VARCHAR(10) NOT NULL,
-		horario_fim VARCHAR(10) NOT NULL,
-		CONSTRAINT unique_alocacao UNIQUE(sala_id, dia_semana, horario_inicio)
+		horario_inicio TIME NOT NULL,
+		horario_fim TIME NOT NULL,
+		ignorar_capacidade BOOLEAN NOT NULL DEFAULT FALSE,
+		disciplina_id INT REFERENCES disciplinas(id) ON DELETE SET NULL,
+		periodo_letivo_id INT NOT NULL REFERENCES periodos_letivos(id),
+		CONSTRAINT alocacoes_horario_valido CHECK (horario_fim > horario_inicio)
 	);
 	`
 	_, err = db.Exec(createAlocacaoTable)
@@ -112,5 +713,488 @@ func MigrateTables(db *sql.DB) {
 		log.Fatalf("Erro ao criar tabela de alocações: %v", err)
 	}
 
+	// Adicionar colunas novas em tabelas de alocações já existentes
+	alterAlocacaoTable := `
+	ALTER TABLE alocacoes ADD COLUMN IF NOT EXISTS ignorar_capacidade BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE alocacoes ADD COLUMN IF NOT EXISTS disciplina_id INT REFERENCES disciplinas(id) ON DELETE SET NULL;
+	UPDATE alocacoes a SET disciplina_id = t.disciplina_id FROM turmas t
+	WHERE a.disciplina_id IS NULL AND a.turma_id = t.id;
+	`
+	_, err = db.Exec(alterAlocacaoTable)
+	if err != nil {
+		log.Fatalf("Erro ao atualizar tabela de alocações: %v", err)
+	}
+
+	// Associar as alocações já existentes ao período letivo que contém a data atual ou, sem ele, ao mais recente
+	vincularPeriodosLetivos := `
+	ALTER TABLE alocacoes ADD COLUMN IF NOT EXISTS periodo_letivo_id INT REFERENCES periodos_letivos(id);
+	UPDATE alocacoes SET periodo_letivo_id = (
+		SELECT id FROM periodos_letivos ORDER BY (CURRENT_DATE BETWEEN data_inicio AND data_fim) DESC, data_inicio DESC LIMIT 1
+	) WHERE periodo_letivo_id IS NULL;
+	ALTER TABLE alocacoes ALTER COLUMN periodo_letivo_id SET NOT NULL;
+	`
+	_, err = db.Exec(vincularPeriodosLetivos)
+	if err != nil {
+		log.Fatalf("Erro ao atualizar tabela de alocações: %v", err)
+	}
+
+	// Adicionar a regra de recorrência das alocações; com os valores padrão, a aula acontece toda semana do período letivo
+	alterRecorrenciaAlocacao := `
+	ALTER TABLE alocacoes ADD COLUMN IF NOT EXISTS intervalo_semanas INT NOT NULL DEFAULT 1 CONSTRAINT alocacoes_intervalo_valido CHECK (intervalo_semanas >= 1);
+	ALTER TABLE alocacoes ADD COLUMN IF NOT EXISTS data_inicio DATE;
+	ALTER TABLE alocacoes ADD COLUMN IF NOT EXISTS data_fim DATE CONSTRAINT alocacoes_data_valida CHECK (data_fim >= data_inicio);
+	ALTER TABLE alocacoes ADD COLUMN IF NOT EXISTS semanas INT[];
+	`
+	_, err = db.Exec(alterRecorrenciaAlocacao)
+	if err != nil {
+		log.Fatalf("Erro ao atualizar tabela de alocações: %v", err)
+	}
+
+	// Converter horários gravados como texto em tabelas de alocações já existentes
+	err = migrarHorariosAlocacoes(db)
+	if err != nil {
+		log.Fatalf("Erro ao migrar horários das alocações: %v", err)
+	}
+
+	// Normalizar os dias da semana já gravados e restringir a coluna à grafia canônica
+	err = migrarDiasSemanaAlocacoes(db)
+	if err != nil {
+		log.Fatalf("Erro ao migrar dias da semana das alocações: %v", err)
+	}
+
+	// Impedir no banco que sala, professor ou turma tenham alocações sobrepostas
+	err = criarRestricoesSobreposicao(db)
+	if err != nil {
+		log.Fatalf("Erro ao criar restrições de sobreposição das alocações: %v", err)
+	}
+
+	// Impedir no banco sobreposições que envolvam alocações com regra de recorrência
+	err = criarVerificacaoRecorrencia(db)
+	if err != nil {
+		log.Fatalf("Erro ao criar verificação de sobreposição das alocações recorrentes: %v", err)
+	}
+
+	// Criar tabela de planos de alocação automática
+	createPlanoAlocacaoTable := `
+	CREATE TABLE IF NOT EXISTS planos_alocacao (
+		token VARCHAR(64) PRIMARY KEY,
+		plano JSONB NOT NULL,
+		criado_em TIMESTAMP NOT NULL DEFAULT NOW(),
+		aplicado_em TIMESTAMP
+	);
+	`
+	_, err = db.Exec(createPlanoAlocacaoTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de planos de alocação: %v", err)
+	}
+
+	// Criar tabela de disponibilidade dos professores
+	createDisponibilidadeTable := `
+	CREATE TABLE IF NOT EXISTS disponibilidades_professor (
+		id SERIAL PRIMARY KEY,
+		professor_id INT NOT NULL REFERENCES professores(id) ON DELETE CASCADE,
+		dia_semana VARCHAR(20) NOT NULL,
+		horario_inicio TIME NOT NULL,
+		horario_fim TIME NOT NULL,
+		tipo VARCHAR(20) NOT NULL,
+		motivo VARCHAR(255) NOT NULL DEFAULT '',
+		CONSTRAINT disponibilidades_professor_horario_valido CHECK (horario_fim > horario_inicio),
+		CONSTRAINT disponibilidades_professor_tipo_valido CHECK (tipo IN ('disponivel', 'indisponivel'))
+	);
+	`
+	_, err = db.Exec(createDisponibilidadeTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de disponibilidade dos professores: %v", err)
+	}
+
+	// Criar tabela de preferências dos professores
+	createPreferenciaTable := `
+	CREATE TABLE IF NOT EXISTS preferencias_professor (
+		professor_id INT PRIMARY KEY REFERENCES professores(id) ON DELETE CASCADE,
+		dias_semana TEXT[] NOT NULL DEFAULT '{}',
+		turnos TEXT[] NOT NULL DEFAULT '{}',
+		blocos TEXT[] NOT NULL DEFAULT '{}',
+		sala_ids INT[] NOT NULL DEFAULT '{}'
+	);
+	`
+	_, err = db.Exec(createPreferenciaTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de preferências dos professores: %v", err)
+	}
+
+	// Criar tabela de bloqueios de salas
+	createBloqueioSalaTable := `
+	CREATE TABLE IF NOT EXISTS bloqueios_sala (
+		id SERIAL PRIMARY KEY,
+		sala_id INT NOT NULL REFERENCES salas(id) ON DELETE CASCADE,
+		dia_semana VARCHAR(20),
+		horario_inicio TIME,
+		horario_fim TIME,
+		data_inicio DATE,
+		data_fim DATE,
+		motivo VARCHAR(255) NOT NULL,
+		CONSTRAINT bloqueios_sala_periodo_informado CHECK (dia_semana IS NOT NULL OR data_inicio IS NOT NULL),
+		CONSTRAINT bloqueios_sala_horario_valido CHECK (horario_fim > horario_inicio),
+		CONSTRAINT bloqueios_sala_data_valida CHECK (data_fim >= data_inicio)
+	);
+	ALTER TABLE alocacoes ADD COLUMN IF NOT EXISTS bloqueio_sala_id INT REFERENCES bloqueios_sala(id) ON DELETE SET NULL;
+	`
+	_, err = db.Exec(createBloqueioSalaTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de bloqueios de salas: %v", err)
+	}
+
+	// Criar tabela de dias não letivos, como feriados e recessos
+	createDiaNaoLetivoTable := `
+	CREATE TABLE IF NOT EXISTS dias_nao_letivos (
+		id SERIAL PRIMARY KEY,
+		data DATE NOT NULL,
+		descricao VARCHAR(255) NOT NULL,
+		tipo VARCHAR(20) NOT NULL DEFAULT 'feriado',
+		CONSTRAINT dias_nao_letivos_data_unica UNIQUE (data),
+		CONSTRAINT dias_nao_letivos_tipo_valido CHECK (tipo IN ('feriado', 'sem_aula'))
+	);
+	`
+	_, err = db.Exec(createDiaNaoLetivoTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de dias não letivos: %v", err)
+	}
+
+	// Criar tabela de exceções das alocações em datas específicas. Cada aula da alocação tem no
+	// máximo uma exceção.
+	createExcecaoAlocacaoTable := `
+	CREATE TABLE IF NOT EXISTS excecoes_alocacao (
+		id SERIAL PRIMARY KEY,
+		alocacao_id INT NOT NULL REFERENCES alocacoes(id) ON DELETE CASCADE,
+		data DATE NOT NULL,
+		tipo VARCHAR(20) NOT NULL,
+		sala_id INT REFERENCES salas(id) ON DELETE CASCADE,
+		nova_data DATE,
+		horario_inicio TIME,
+		horario_fim TIME,
+		motivo VARCHAR(255) NOT NULL DEFAULT '',
+		CONSTRAINT excecoes_alocacao_data_unica UNIQUE (alocacao_id, data),
+		CONSTRAINT excecoes_alocacao_tipo_valido CHECK (tipo IN ('cancelada', 'troca_sala', 'reposicao')),
+		CONSTRAINT excecoes_alocacao_horario_valido CHECK (horario_fim > horario_inicio)
+	);
+	`
+	_, err = db.Exec(createExcecaoAlocacaoTable)
+	if err != nil {
+		log.Fatalf("Erro ao criar tabela de exceções das alocações: %v", err)
+	}
+
 	fmt.Println("Tabelas criadas com sucesso")
 }
+
+// migrarHorariosAlocacoes converte as colunas de horário de alocacoes de VARCHAR para TIME.
+// Os valores existentes são normalizados para HH:MM antes da conversão, para que horários
+// como "9:00" ou "9h" sejam aceitos. Se algum valor não puder ser lido, as alocações afetadas são
+// registradas em um aviso, ficam como estão e as colunas continuam como texto até que os dados
+// sejam corrigidos, sem impedir a inicialização.
+func migrarHorariosAlocacoes(db *sql.DB) error {
+	var tipo string
+	err := db.QueryRow(`
+		SELECT data_type FROM information_schema.columns 
+		WHERE table_name = 'alocacoes' AND column_name = 'horario_inicio'
+	`).Scan(&tipo)
+	if err != nil {
+		return err
+	}
+	if tipo == "time without time zone" {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, horario_inicio, horario_fim FROM alocacoes")
+	if err != nil {
+		return err
+	}
+
+	type horarioGravado struct {
+		id          int
+		inicio, fim Horario
+	}
+
+	var horarios []horarioGravado
+	var invalidas []string
+	for rows.Next() {
+		var id int
+		var inicio, fim string
+		if err := rows.Scan(&id, &inicio, &fim); err != nil {
+			rows.Close()
+			return err
+		}
+
+		h := horarioGravado{id: id}
+		var errInicio, errFim error
+		h.inicio, errInicio = ParseHorario(inicio)
+		h.fim, errFim = ParseHorario(fim)
+		if errInicio != nil || errFim != nil {
+			invalidas = append(invalidas, fmt.Sprintf("%d (%s-%s)", id, inicio, fim))
+			continue
+		}
+		horarios = append(horarios, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, h := range horarios {
+		_, err = tx.Exec("UPDATE alocacoes SET horario_inicio = $1, horario_fim = $2 WHERE id = $3", h.inicio.String(), h.fim.String(), h.id)
+		if err != nil {
+			return err
+		}
+		if ValidarIntervalo(h.inicio, h.fim) != nil {
+			log.Printf("Alocação %d possui horario_fim anterior ou igual a horario_inicio (%s-%s)", h.id, h.inicio, h.fim)
+		}
+	}
+
+	// Os horários legíveis ficam normalizados, mas a conversão das colunas espera a correção dos demais
+	if len(invalidas) > 0 {
+		log.Printf("Não foi possível converter os horários das alocações para TIME, corrija os horários das alocações %s", strings.Join(invalidas, ", "))
+		return tx.Commit()
+	}
+
+	// A restrição só é validada para novas linhas, para não impedir a migração de dados antigos inconsistentes
+	_, err = tx.Exec(`
+		ALTER TABLE alocacoes 
+			ALTER COLUMN horario_inicio TYPE TIME USING horario_inicio::time,
+			ALTER COLUMN horario_fim TYPE TIME USING horario_fim::time;
+		ALTER TABLE alocacoes ADD CONSTRAINT alocacoes_horario_valido CHECK (horario_fim > horario_inicio) NOT VALID;
+	`)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrarDiasSemanaAlocacoes converte os dias da semana gravados em alocacoes para a grafia
+// canônica e adiciona uma restrição que só aceita essa grafia. Se algum valor não for
+// reconhecido, as alocações afetadas são registradas em um aviso e ficam como estão, e a restrição
+// só é criada depois que os dados forem corrigidos, sem impedir a inicialização.
+func migrarDiasSemanaAlocacoes(db *sql.DB) error {
+	var existe bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM pg_constraint WHERE conname = 'alocacoes_dia_semana_valido')").Scan(&existe)
+	if err != nil {
+		return err
+	}
+	if existe {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT DISTINCT dia_semana FROM alocacoes")
+	if err != nil {
+		return err
+	}
+
+	var grafias []string
+	for rows.Next() {
+		var grafia string
+		if err := rows.Scan(&grafia); err != nil {
+			rows.Close()
+			return err
+		}
+		grafias = append(grafias, grafia)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var invalidas []string
+	for _, grafia := range grafias {
+		dia, err := ParseDiaSemana(grafia)
+		if err != nil {
+			ids, err := idsAlocacoesComDia(tx, grafia)
+			if err != nil {
+				return err
+			}
+			invalidas = append(invalidas, fmt.Sprintf("%s (%q)", ids, grafia))
+			continue
+		}
+		if string(dia) == grafia {
+			continue
+		}
+
+		_, err = tx.Exec("UPDATE alocacoes SET dia_semana = $1 WHERE dia_semana = $2", dia, grafia)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(invalidas) > 0 {
+		log.Printf("Não foi possível restringir os dias da semana das alocações, corrija o dia das alocações %s", strings.Join(invalidas, ", "))
+		return tx.Commit()
+	}
+
+	canonicos := make([]string, 0, len(DiasSemana))
+	for _, dia := range DiasSemana {
+		canonicos = append(canonicos, "'"+string(dia)+"'")
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(
+		"ALTER TABLE alocacoes ADD CONSTRAINT alocacoes_dia_semana_valido CHECK (dia_semana IN (%s))",
+		strings.Join(canonicos, ", "),
+	))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// idsAlocacoesComDia lista, separados por vírgula, os IDs das alocações gravadas com o dia da semana informado
+func idsAlocacoesComDia(tx *sql.Tx, grafia string) (string, error) {
+	var ids string
+	err := tx.QueryRow("SELECT string_agg(id::text, ', ' ORDER BY id) FROM alocacoes WHERE dia_semana = $1", grafia).Scan(&ids)
+	return ids, err
+}
+
+// criarVerificacaoRecorrencia cria o gatilho de restrição que recusa, no próprio banco, alocações
+// que ocupem a mesma sala, professor ou turma de outra alocação do mesmo período letivo e dia, com
+// horário sobreposto e alguma data de aula em comum, quando alguma das duas tem regra de
+// recorrência. As datas de cada alocação são calculadas como na API: a partir da primeira aula no
+// período letivo, limitado às datas da regra, a cada intervalo_semanas ou nas semanas informadas.
+// O gatilho obtém os mesmos bloqueios consultivos da API (chaves 7000 a 7002, na ordem sala,
+// professor e turma), para que gravações simultâneas sejam verificadas uma de cada vez, e recusa
+// a gravação com o código e o nome da restrição de exclusão do recurso em conflito.
+func criarVerificacaoRecorrencia(db *sql.DB) error {
+	dias := make([]string, 0, len(DiasSemana))
+	for _, dia := range DiasSemana {
+		dias = append(dias, "'"+string(dia)+"'")
+	}
+
+	_, err := db.Exec(fmt.Sprintf(`
+	CREATE OR REPLACE FUNCTION datas_alocacao(periodo_id INT, dia TEXT, intervalo INT, inicio DATE, fim DATE, semanas INT[])
+	RETURNS SETOF DATE AS $$
+		SELECT d::DATE
+		FROM periodos_letivos p,
+			LATERAL (SELECT
+				GREATEST(p.data_inicio, COALESCE($4, p.data_inicio)) AS primeira,
+				LEAST(p.data_fim, COALESCE($5, p.data_fim)) AS ultima,
+				p.data_inicio - (EXTRACT(ISODOW FROM p.data_inicio)::INT - 1) AS primeira_segunda
+			) l,
+			LATERAL generate_series(
+				(l.primeira + (array_position(ARRAY[%s], $2) - EXTRACT(ISODOW FROM l.primeira)::INT + 7) %% 7)::TIMESTAMP,
+				l.ultima::TIMESTAMP,
+				INTERVAL '7 days'
+			) WITH ORDINALITY AS s(d, n)
+		WHERE p.id = $1
+			AND (n - 1) %% GREATEST($3, 1) = 0
+			AND ($6 IS NULL OR cardinality($6) = 0 OR (d::DATE - l.primeira_segunda) / 7 + 1 = ANY($6))
+	$$ LANGUAGE sql STABLE;
+
+	CREATE OR REPLACE FUNCTION verificar_sobreposicao_recorrente() RETURNS TRIGGER AS $$
+	DECLARE
+		restricao TEXT;
+	BEGIN
+		PERFORM pg_advisory_xact_lock(7000, NEW.sala_id), pg_advisory_xact_lock(7001, NEW.professor_id), pg_advisory_xact_lock(7002, NEW.turma_id);
+
+		SELECT CASE
+			WHEN o.sala_id = NEW.sala_id THEN 'alocacoes_sala_sem_sobreposicao'
+			WHEN o.professor_id = NEW.professor_id THEN 'alocacoes_professor_sem_sobreposicao'
+			ELSE 'alocacoes_turma_sem_sobreposicao'
+		END INTO restricao
+		FROM alocacoes o
+		WHERE o.id <> NEW.id AND o.periodo_letivo_id = NEW.periodo_letivo_id AND o.dia_semana = NEW.dia_semana
+			AND o.horario_inicio < NEW.horario_fim AND o.horario_fim > NEW.horario_inicio
+			AND (o.sala_id = NEW.sala_id OR o.professor_id = NEW.professor_id OR o.turma_id = NEW.turma_id)
+			AND NOT (o.intervalo_semanas = 1 AND o.data_inicio IS NULL AND o.data_fim IS NULL AND o.semanas IS NULL
+				AND NEW.intervalo_semanas = 1 AND NEW.data_inicio IS NULL AND NEW.data_fim IS NULL AND NEW.semanas IS NULL)
+			AND EXISTS (
+				SELECT datas_alocacao(o.periodo_letivo_id, o.dia_semana, o.intervalo_semanas, o.data_inicio, o.data_fim, o.semanas)
+				INTERSECT
+				SELECT datas_alocacao(NEW.periodo_letivo_id, NEW.dia_semana, NEW.intervalo_semanas, NEW.data_inicio, NEW.data_fim, NEW.semanas)
+			)
+		LIMIT 1;
+
+		IF restricao IS NOT NULL THEN
+			RAISE EXCEPTION 'a alocação %% tem aula em uma data em comum com outra alocação no mesmo horário', NEW.id
+				USING ERRCODE = 'exclusion_violation', CONSTRAINT = restricao;
+		END IF;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql;
+
+	DROP TRIGGER IF EXISTS alocacoes_recorrentes_sem_sobreposicao ON alocacoes;
+	CREATE CONSTRAINT TRIGGER alocacoes_recorrentes_sem_sobreposicao
+		AFTER INSERT OR UPDATE OF periodo_letivo_id, sala_id, professor_id, turma_id, dia_semana, horario_inicio, horario_fim,
+			intervalo_semanas, data_inicio, data_fim, semanas ON alocacoes
+		FOR EACH ROW EXECUTE FUNCTION verificar_sobreposicao_recorrente();
+	`, strings.Join(dias, ", ")))
+	return err
+}
+
+// restricoesSobreposicao associa o nome de cada restrição de exclusão de alocacoes à coluna do recurso
+var restricoesSobreposicao = []struct {
+	nome   string
+	coluna string
+}{
+	{"alocacoes_sala_sem_sobreposicao", "sala_id"},
+	{"alocacoes_professor_sem_sobreposicao", "professor_id"},
+	{"alocacoes_turma_sem_sobreposicao", "turma_id"},
+}
+
+// criarRestricoesSobreposicao cria restrições de exclusão que impedem, no próprio banco, que a
+// mesma sala, professor ou turma tenha duas alocações no mesmo período letivo e dia com horários
+// sobrepostos. Elas substituem a antiga restrição unique_alocacao, que só bloqueava horários de
+// início iguais, e são recriadas se ainda não se limitam às alocações semanais: alocações com
+// regra de recorrência só colidem se tiverem alguma data em comum, o que é verificado pelo gatilho
+// de criarVerificacaoRecorrencia. Se já houver alocações sobrepostas gravadas, a restrição
+// correspondente não é criada e um aviso é registrado, para que os dados possam ser corrigidos
+// sem impedir a inicialização.
+func criarRestricoesSobreposicao(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE EXTENSION IF NOT EXISTS btree_gist;
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'timerange') THEN
+			CREATE TYPE timerange AS RANGE (subtype = time);
+		END IF;
+	END
+	$$;
+	ALTER TABLE alocacoes DROP CONSTRAINT IF EXISTS unique_alocacao;
+	`)
+	if err != nil {
+		return err
+	}
+
+	for _, restricao := range restricoesSobreposicao {
+		var definicao string
+		err := db.QueryRow("SELECT pg_get_constraintdef(oid) FROM pg_constraint WHERE conname = $1", restricao.nome).Scan(&definicao)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if strings.Contains(definicao, "intervalo_semanas") {
+			continue
+		}
+
+		_, err = db.Exec(fmt.Sprintf(`
+			ALTER TABLE alocacoes DROP CONSTRAINT IF EXISTS %[1]s;
+			ALTER TABLE alocacoes ADD CONSTRAINT %[1]s EXCLUDE USING gist (
+				periodo_letivo_id WITH =,
+				%[2]s WITH =,
+				dia_semana WITH =,
+				timerange(horario_inicio, horario_fim) WITH &&
+			) WHERE (intervalo_semanas = 1 AND data_inicio IS NULL AND data_fim IS NULL AND semanas IS NULL)
+		`, restricao.nome, restricao.coluna))
+		if err != nil {
+			log.Printf("Não foi possível criar a restrição %s, verifique se há alocações sobrepostas: %v", restricao.nome, err)
+		}
+	}
+
+	return nil
+}
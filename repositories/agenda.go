@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrAgendaInvalida indica que o intervalo de datas da consulta da agenda é inválido
+var ErrAgendaInvalida = errors.New("consulta da agenda inválida")
+
+// diasMaximosAgenda é o maior intervalo, em dias, de uma consulta da agenda
+const diasMaximosAgenda = 366
+
+// GetAgenda retorna as aulas de cada data do intervalo do filtro, com os dias não letivos e as
+// exceções das alocações aplicados. Sem data final, a consulta cobre a semana que começa na data inicial.
+func (r *AlocacaoRepository) GetAgenda(filtro models.FiltroAgenda) ([]models.AulaAgenda, error) {
+	if filtro.DataInicio.IsZero() {
+		return nil, fmt.Errorf("%w: data_inicio é obrigatória", ErrAgendaInvalida)
+	}
+	if filtro.DataFim.IsZero() {
+		filtro.DataFim = filtro.DataInicio.AdicionarDias(6)
+	}
+	if filtro.DataFim.Before(filtro.DataInicio.Time) {
+		return nil, fmt.Errorf("%w: data_fim (%s) não pode ser anterior a data_inicio (%s)", ErrAgendaInvalida, filtro.DataFim, filtro.DataInicio)
+	}
+	if filtro.DataInicio.AdicionarDias(diasMaximosAgenda).Before(filtro.DataFim.Time) {
+		return nil, fmt.Errorf("%w: o intervalo não pode passar de %d dias", ErrAgendaInvalida, diasMaximosAgenda)
+	}
+
+	aulas, err := gerarAgenda(r.DB, filtro.DataInicio, filtro.DataFim, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	filtradas := []models.AulaAgenda{}
+	for _, a := range aulas {
+		if filtro.SalaID != 0 && a.SalaID != filtro.SalaID {
+			continue
+		}
+		if filtro.ProfessorID != 0 && a.ProfessorID != filtro.ProfessorID {
+			continue
+		}
+		if filtro.TurmaID != 0 && a.TurmaID != filtro.TurmaID {
+			continue
+		}
+		filtradas = append(filtradas, a)
+	}
+	return filtradas, nil
+}
+
+// chaveAula identifica a aula de uma alocação em uma data
+type chaveAula struct {
+	alocacaoID int
+	data       string
+}
+
+// gerarAgenda expande as alocações nas datas do intervalo em que cada uma tem aula, de acordo com o
+// período letivo e a regra de recorrência, e aplica os dias não letivos e as exceções, exceto a
+// exceção informada. As reposições marcadas para datas do intervalo entram como aulas próprias.
+func gerarAgenda(db executor, inicio, fim models.Data, ignorarExcecaoID int) ([]models.AulaAgenda, error) {
+	periodos, err := periodosLetivosPorID(db)
+	if err != nil {
+		return nil, err
+	}
+
+	alocacoes, err := queryAlocacoes(db, ` WHERE a.periodo_letivo_id IN (
+		SELECT id FROM periodos_letivos WHERE data_inicio <= $2 AND data_fim >= $1
+	)`, inicio, fim)
+	if err != nil {
+		return nil, err
+	}
+
+	naoLetivos, err := diasNaoLetivosEntre(db, inicio, fim)
+	if err != nil {
+		return nil, err
+	}
+
+	excecoes, err := queryExcecoes(db, " WHERE (data BETWEEN $1 AND $2 OR nova_data BETWEEN $1 AND $2) AND id <> $3", inicio, fim, ignorarExcecaoID)
+	if err != nil {
+		return nil, err
+	}
+	excecaoDaAula := make(map[chaveAula]models.ExcecaoAlocacao, len(excecoes))
+	for _, e := range excecoes {
+		excecaoDaAula[chaveAula{e.AlocacaoID, e.Data.String()}] = e
+	}
+
+	// Salas das trocas e reposições, lidas uma vez cada
+	salas := make(map[int]models.Sala)
+	mudarSala := func(aula *models.AulaAgenda, salaID *int) error {
+		if salaID == nil || *salaID == aula.SalaID {
+			return nil
+		}
+		s, ok := salas[*salaID]
+		if !ok {
+			if s, err = getSalaByID(db, *salaID); err != nil {
+				return err
+			}
+			salas[s.ID] = s
+		}
+		aula.SalaID, aula.Sala = s.ID, s.Numero
+		return nil
+	}
+
+	aulas := []models.AulaAgenda{}
+	porID := make(map[int]models.Alocacao, len(alocacoes))
+	for _, a := range alocacoes {
+		porID[a.ID] = a
+
+		for _, d := range datasDaAlocacao(a, periodos[a.PeriodoLetivoID]) {
+			if d.Before(inicio.Time) || d.After(fim.Time) {
+				continue
+			}
+
+			aula := novaAulaAgenda(a, d)
+			if e, ok := excecaoDaAula[chaveAula{a.ID, d.String()}]; ok {
+				aula.ExcecaoID, aula.Motivo = &e.ID, e.Motivo
+				switch e.Tipo {
+				case models.ExcecaoCancelada:
+					aula.Situacao = models.AulaCancelada
+				case models.ExcecaoTrocaSala:
+					aula.Situacao = models.AulaTrocaSala
+					if err := mudarSala(&aula, e.SalaID); err != nil {
+						return nil, err
+					}
+				case models.ExcecaoReposicao:
+					aula.Situacao = models.AulaRemarcada
+				}
+			}
+			if dia, ok := naoLetivos[d.String()]; ok && aula.Acontece() {
+				aula.Situacao, aula.Motivo = models.AulaNaoLetivo, dia.Descricao
+			}
+			aulas = append(aulas, aula)
+		}
+	}
+
+	for _, e := range excecoes {
+		if e.Tipo != models.ExcecaoReposicao || e.NovaData == nil || e.NovaData.Before(inicio.Time) || e.NovaData.After(fim.Time) {
+			continue
+		}
+		a, ok := porID[e.AlocacaoID]
+		if !ok {
+			continue
+		}
+
+		aula := novaAulaAgenda(a, *e.NovaData)
+		aula.Situacao, aula.ExcecaoID, aula.Motivo = models.AulaReposicao, &e.ID, e.Motivo
+		if e.HorarioInicio != nil {
+			aula.HorarioInicio, aula.HorarioFim = *e.HorarioInicio, *e.HorarioFim
+		}
+		if err := mudarSala(&aula, e.SalaID); err != nil {
+			return nil, err
+		}
+		if dia, ok := naoLetivos[e.NovaData.String()]; ok {
+			aula.Situacao, aula.Motivo = models.AulaNaoLetivo, dia.Descricao
+		}
+		aulas = append(aulas, aula)
+	}
+
+	sort.SliceStable(aulas, func(i, j int) bool {
+		if !aulas[i].Data.Equal(aulas[j].Data.Time) {
+			return aulas[i].Data.Before(aulas[j].Data.Time)
+		}
+		if aulas[i].HorarioInicio != aulas[j].HorarioInicio {
+			return aulas[i].HorarioInicio < aulas[j].HorarioInicio
+		}
+		return aulas[i].Sala < aulas[j].Sala
+	})
+
+	return aulas, nil
+}
+
+// novaAulaAgenda cria a aula de uma alocação na data informada, sem exceções
+func novaAulaAgenda(a models.Alocacao, data models.Data) models.AulaAgenda {
+	aula := models.AulaAgenda{
+		Data:          data,
+		DiaSemana:     data.DiaSemana(),
+		HorarioInicio: a.HorarioInicio,
+		HorarioFim:    a.HorarioFim,
+		Situacao:      models.AulaNormal,
+		AlocacaoID:    a.ID,
+		ProfessorID:   a.ProfessorID,
+		Professor:     a.Professor.Nome,
+		SalaID:        a.SalaID,
+		Sala:          a.Sala.Numero,
+		TurmaID:       a.TurmaID,
+		Turma:         a.Turma.Nome,
+		DisciplinaID:  a.DisciplinaID,
+	}
+	if a.Disciplina != nil {
+		aula.Disciplina = a.Disciplina.Nome
+	}
+	return aula
+}
+
+// proximaData retorna a primeira data, a partir da informada, que cai no dia da semana
+func proximaData(data models.Data, dia models.DiaSemana) models.Data {
+	return data.AdicionarDias((dia.Numero() - data.DiaSemana().Numero() + 7) % 7)
+}
@@ -0,0 +1,539 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// candidatoAlocacao representa uma combinação professor/sala/turma que respeita
+// todas as restrições obrigatórias, junto com a sua pontuação de adequação
+type candidatoAlocacao struct {
+	Professor models.Professor
+	Sala      models.Sala
+	Turma     models.Turma
+	Pontuacao int
+}
+
+// ErrPlanoNaoEncontrado indica que o token informado não corresponde a nenhum plano de alocação
+var ErrPlanoNaoEncontrado = errors.New("plano de alocação não encontrado")
+
+// ErrPlanoJaAplicado indica que o plano de alocação já foi aplicado anteriormente
+var ErrPlanoJaAplicado = errors.New("plano de alocação já foi aplicado")
+
+// funcaoPontuacao calcula a adequação de uma combinação viável de professor, sala e turma
+type funcaoPontuacao func(p models.Professor, s models.Sala, t models.Turma) int
+
+// OrganizarAlocacoesAutomaticas organiza alocações automaticamente para um dia e horário específicos
+// do período letivo informado (zero para o atual).
+// No modo transacional, todas as alocações são gravadas em uma única transação e qualquer falha
+// desfaz o lote inteiro; caso contrário, as alocações válidas são mantidas mesmo se outras falharem.
+func (r *AlocacaoRepository) OrganizarAlocacoesAutomaticas(periodoLetivoID int, diaSemana models.DiaSemana, horarioInicio, horarioFim models.Horario, transacional bool) (models.ResultadoAlocacaoAutomatica, error) {
+	plano, err := r.planejarAlocacoes(periodoLetivoID, diaSemana, horarioInicio, horarioFim)
+	if err != nil {
+		return models.ResultadoAlocacaoAutomatica{}, err
+	}
+
+	// Sem combinações viáveis nada é gravado, mas o resultado ainda traz o motivo de cada recurso
+	var resultado models.ResultadoAlocacaoAutomatica
+	if !transacional || len(plano.Alocacoes) == 0 {
+		resultado = gravarLote(r.DB, plano.Alocacoes, transacional)
+	} else {
+		tx, err := r.DB.Begin()
+		if err != nil {
+			return models.ResultadoAlocacaoAutomatica{}, err
+		}
+		defer tx.Rollback()
+
+		resultado, err = finalizarLote(tx, gravarLote(tx, plano.Alocacoes, true))
+		if err != nil {
+			return models.ResultadoAlocacaoAutomatica{}, err
+		}
+	}
+
+	resultado.NaoAlocados = plano.NaoAlocados
+	return r.relatarSatisfacao(resultado)
+}
+
+// SimularAlocacoesAutomaticas calcula as alocações automáticas sem gravá-las e guarda o plano
+// resultante, que pode ser aplicado exatamente como foi simulado por meio do token retornado
+func (r *AlocacaoRepository) SimularAlocacoesAutomaticas(periodoLetivoID int, diaSemana models.DiaSemana, horarioInicio, horarioFim models.Horario) (models.PlanoAlocacao, error) {
+	plano, err := r.planejarAlocacoes(periodoLetivoID, diaSemana, horarioInicio, horarioFim)
+	if err != nil {
+		return models.PlanoAlocacao{}, err
+	}
+
+	return r.salvarPlano(plano)
+}
+
+// salvarPlano gera o token do plano e o guarda para ser aplicado depois
+func (r *AlocacaoRepository) salvarPlano(plano models.PlanoAlocacao) (models.PlanoAlocacao, error) {
+	var err error
+	plano.Token, err = gerarToken()
+	if err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao gerar token do plano: %v", err)
+	}
+	plano.CriadoEm = time.Now()
+
+	conteudo, err := json.Marshal(plano)
+	if err != nil {
+		return models.PlanoAlocacao{}, err
+	}
+
+	_, err = r.DB.Exec("INSERT INTO planos_alocacao (token, plano, criado_em) VALUES ($1, $2, $3)", plano.Token, conteudo, plano.CriadoEm)
+	if err != nil {
+		return models.PlanoAlocacao{}, err
+	}
+
+	return plano, nil
+}
+
+// AplicarPlanoAlocacao cria as alocações de um plano simulado anteriormente. No modo transacional,
+// se alguma alocação falhar o plano continua disponível para ser aplicado novamente; fora dele, o
+// plano só é consumido se ao menos uma alocação for gravada.
+func (r *AlocacaoRepository) AplicarPlanoAlocacao(token string, transacional bool) (models.ResultadoAlocacaoAutomatica, error) {
+	if !transacional {
+		plano, err := reservarPlano(r.DB, token)
+		if err != nil {
+			return models.ResultadoAlocacaoAutomatica{}, err
+		}
+		resultado := gravarLote(r.DB, plano.Alocacoes, false)
+		// Sem nenhuma alocação gravada, o plano volta a ficar disponível, como no modo transacional
+		if len(resultado.Alocacoes) == 0 {
+			if err := liberarPlano(r.DB, token); err != nil {
+				return models.ResultadoAlocacaoAutomatica{}, err
+			}
+		}
+		resultado.NaoAlocados = plano.NaoAlocados
+		return r.relatarSatisfacao(resultado)
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return models.ResultadoAlocacaoAutomatica{}, err
+	}
+	defer tx.Rollback()
+
+	plano, err := reservarPlano(tx, token)
+	if err != nil {
+		return models.ResultadoAlocacaoAutomatica{}, err
+	}
+
+	resultado, err := finalizarLote(tx, gravarLote(tx, plano.Alocacoes, true))
+	if err != nil {
+		return models.ResultadoAlocacaoAutomatica{}, err
+	}
+
+	resultado.NaoAlocados = plano.NaoAlocados
+	return r.relatarSatisfacao(resultado)
+}
+
+// reservarPlano lê o plano do token e o marca como aplicado na mesma instrução,
+// para que um plano não seja aplicado duas vezes
+func reservarPlano(db executor, token string) (models.PlanoAlocacao, error) {
+	var conteudo []byte
+	err := db.QueryRow(`
+		UPDATE planos_alocacao SET aplicado_em = NOW() 
+		WHERE token = $1 AND aplicado_em IS NULL 
+		RETURNING plano
+	`, token).Scan(&conteudo)
+	if err == sql.ErrNoRows {
+		var existe bool
+		err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM planos_alocacao WHERE token = $1)", token).Scan(&existe)
+		if err != nil {
+			return models.PlanoAlocacao{}, err
+		}
+		if existe {
+			return models.PlanoAlocacao{}, ErrPlanoJaAplicado
+		}
+		return models.PlanoAlocacao{}, ErrPlanoNaoEncontrado
+	}
+	if err != nil {
+		return models.PlanoAlocacao{}, err
+	}
+
+	var plano models.PlanoAlocacao
+	if err := json.Unmarshal(conteudo, &plano); err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao ler plano de alocação: %v", err)
+	}
+
+	return plano, nil
+}
+
+// liberarPlano desfaz a reserva feita por reservarPlano, para que o plano possa ser aplicado novamente
+func liberarPlano(db executor, token string) error {
+	_, err := db.Exec("UPDATE planos_alocacao SET aplicado_em = NULL WHERE token = $1", token)
+	return err
+}
+
+// gravarLote grava as alocações informadas e registra o erro de cada item que não puder ser criado.
+// Dentro de uma transação, cada item usa um savepoint para que a falha de um não aborte a
+// transação e os erros de todos os itens possam ser relatados.
+func gravarLote(db executor, alocacoes []models.Alocacao, transacional bool) models.ResultadoAlocacaoAutomatica {
+	resultado := models.ResultadoAlocacaoAutomatica{
+		Alocacoes:    []models.Alocacao{},
+		Erros:        []models.ErroAlocacao{},
+		Transacional: transacional,
+	}
+
+	for _, a := range alocacoes {
+		alocacaoCriada, err := criarItemDoLote(db, a)
+		if err != nil {
+			resultado.Erros = append(resultado.Erros, models.ErroAlocacao{Alocacao: a, Erro: err.Error()})
+			continue
+		}
+
+		resultado.Alocacoes = append(resultado.Alocacoes, alocacaoCriada)
+	}
+
+	return resultado
+}
+
+// criarItemDoLote cria uma alocação do lote. Fora do modo transacional, cada item usa a sua própria
+// transação; dentro de uma transação, o item é isolado por um savepoint.
+func criarItemDoLote(db executor, a models.Alocacao) (models.Alocacao, error) {
+	if conn, ok := db.(*sql.DB); ok {
+		return (&AlocacaoRepository{DB: conn}).Create(a)
+	}
+
+	if _, err := db.Exec("SAVEPOINT item_lote"); err != nil {
+		return models.Alocacao{}, err
+	}
+
+	alocacaoCriada, err := createAlocacao(db, a)
+	if err != nil {
+		if _, errRollback := db.Exec("ROLLBACK TO SAVEPOINT item_lote"); errRollback != nil {
+			return models.Alocacao{}, errRollback
+		}
+		return models.Alocacao{}, err
+	}
+
+	_, err = db.Exec("RELEASE SAVEPOINT item_lote")
+	return alocacaoCriada, err
+}
+
+// finalizarLote confirma a transação do lote se todos os itens foram gravados, ou a desfaz por completo
+func finalizarLote(tx *sql.Tx, resultado models.ResultadoAlocacaoAutomatica) (models.ResultadoAlocacaoAutomatica, error) {
+	if len(resultado.Erros) > 0 {
+		if err := tx.Rollback(); err != nil {
+			return models.ResultadoAlocacaoAutomatica{}, err
+		}
+		resultado.Alocacoes = []models.Alocacao{}
+		resultado.Revertido = true
+		return resultado, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ResultadoAlocacaoAutomatica{}, err
+	}
+
+	return resultado, nil
+}
+
+// planejarAlocacoes calcula as alocações automáticas para um dia e horário do período letivo sem gravá-las
+func (r *AlocacaoRepository) planejarAlocacoes(periodoLetivoID int, diaSemana models.DiaSemana, horarioInicio, horarioFim models.Horario) (models.PlanoAlocacao, error) {
+	if err := validarAlocacao(models.Alocacao{DiaSemana: diaSemana, HorarioInicio: horarioInicio, HorarioFim: horarioFim}); err != nil {
+		return models.PlanoAlocacao{}, err
+	}
+
+	periodo, err := resolverPeriodoLetivo(r.DB, periodoLetivoID)
+	if err != nil {
+		return models.PlanoAlocacao{}, err
+	}
+
+	// 1. Obter todos os professores disponíveis
+	professores, err := r.getProfessoresDisponiveis(periodo, diaSemana, horarioInicio, horarioFim)
+	if err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter professores disponíveis: %v", err)
+	}
+
+	// 2. Obter todas as salas disponíveis
+	salas, err := r.getSalasDisponiveis(periodo, diaSemana, horarioInicio, horarioFim)
+	if err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter salas disponíveis: %v", err)
+	}
+
+	// 3. Obter todas as turmas disponíveis
+	turmas, err := r.getTurmasDisponiveis(periodo, diaSemana, horarioInicio, horarioFim)
+	if err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter turmas disponíveis: %v", err)
+	}
+	if err := aplicarEquipamentosDisciplinas(r.DB, turmas); err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter equipamentos exigidos pelas disciplinas: %v", err)
+	}
+
+	// 4. Combinar os recursos respeitando as restrições e favorecendo as preferências dos professores
+	preferencias, err := carregarPreferencias(r.DB)
+	if err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter preferências dos professores: %v", err)
+	}
+	aula := models.HorarioAula{DiaSemana: diaSemana, HorarioInicio: horarioInicio, HorarioFim: horarioFim}
+	candidatos, naoAlocados := combinarRecursos(professores, salas, turmas, preferencias.pontuar(aula, pontuarCandidato))
+
+	plano := models.PlanoAlocacao{
+		PeriodoLetivoID: periodo.ID,
+		DiaSemana:       diaSemana,
+		HorarioInicio:   horarioInicio,
+		HorarioFim:      horarioFim,
+		Alocacoes:       []models.Alocacao{},
+		NaoAlocados:     naoAlocados,
+	}
+	for _, c := range candidatos {
+		plano.Alocacoes = append(plano.Alocacoes, models.Alocacao{
+			ProfessorID:     c.Professor.ID,
+			SalaID:          c.Sala.ID,
+			TurmaID:         c.Turma.ID,
+			DisciplinaID:    c.Turma.DisciplinaID,
+			DiaSemana:       diaSemana,
+			HorarioInicio:   horarioInicio,
+			HorarioFim:      horarioFim,
+			PeriodoLetivoID: periodo.ID,
+			Professor:       c.Professor,
+			Sala:            c.Sala,
+			Turma:           c.Turma,
+		})
+	}
+	plano.Satisfacao = preferencias.satisfacao(plano.Alocacoes)
+
+	return plano, nil
+}
+
+// combinarRecursos escolhe, para cada turma, o melhor par professor/sala que respeite
+// as restrições obrigatórias. As turmas com menos opções viáveis são atendidas primeiro,
+// para que as turmas mais fáceis de encaixar não consumam os recursos das mais difíceis.
+// Também retorna os recursos que ficaram sem alocação, com o motivo de cada um.
+func combinarRecursos(professores []models.Professor, salas []models.Sala, turmas []models.Turma, pontuar funcaoPontuacao) ([]candidatoAlocacao, []models.RecursoNaoAlocado) {
+	type opcoesTurma struct {
+		turma       models.Turma
+		salas       []models.Sala
+		professores []models.Professor
+	}
+
+	naoAlocados := []models.RecursoNaoAlocado{}
+	salasCompativeis := make(map[int]bool)
+	professoresCompativeis := make(map[int]bool)
+
+	var opcoes []opcoesTurma
+	for _, t := range turmas {
+		o := opcoesTurma{turma: t}
+		for _, s := range salas {
+			if salaAtendeTurma(s, t) {
+				o.salas = append(o.salas, s)
+			}
+		}
+		for _, p := range professores {
+			if professorAtendeTurma(p, t) {
+				o.professores = append(o.professores, p)
+			}
+		}
+
+		if len(o.salas) == 0 || len(o.professores) == 0 {
+			naoAlocados = append(naoAlocados, models.RecursoNaoAlocado{
+				Tipo: "turma", ID: t.ID, Nome: t.Nome, Motivo: motivoTurmaSemOpcoes(t, salas, o.salas, o.professores),
+			})
+			continue
+		}
+
+		for _, s := range o.salas {
+			salasCompativeis[s.ID] = true
+		}
+		for _, p := range o.professores {
+			professoresCompativeis[p.ID] = true
+		}
+		opcoes = append(opcoes, o)
+	}
+
+	sort.SliceStable(opcoes, func(i, j int) bool {
+		a, b := opcoes[i], opcoes[j]
+		if len(a.salas)*len(a.professores) != len(b.salas)*len(b.professores) {
+			return len(a.salas)*len(a.professores) < len(b.salas)*len(b.professores)
+		}
+		if a.turma.QuantAlunos != b.turma.QuantAlunos {
+			return a.turma.QuantAlunos > b.turma.QuantAlunos
+		}
+		return a.turma.ID < b.turma.ID
+	})
+
+	salasUsadas := make(map[int]bool)
+	professoresUsados := make(map[int]bool)
+
+	var escolhidos []candidatoAlocacao
+	for _, o := range opcoes {
+		melhor, ok := candidatoAlocacao{}, false
+		for _, s := range o.salas {
+			if salasUsadas[s.ID] {
+				continue
+			}
+			for _, p := range o.professores {
+				if professoresUsados[p.ID] {
+					continue
+				}
+				c := candidatoAlocacao{Professor: p, Sala: s, Turma: o.turma, Pontuacao: pontuar(p, s, o.turma)}
+				if !ok || c.Pontuacao > melhor.Pontuacao {
+					melhor, ok = c, true
+				}
+			}
+		}
+		if !ok {
+			naoAlocados = append(naoAlocados, models.RecursoNaoAlocado{
+				Tipo: "turma", ID: o.turma.ID, Nome: o.turma.Nome,
+				Motivo: "as salas e professores compatíveis já foram alocados a outras turmas",
+			})
+			continue
+		}
+
+		salasUsadas[melhor.Sala.ID] = true
+		professoresUsados[melhor.Professor.ID] = true
+		escolhidos = append(escolhidos, melhor)
+	}
+
+	for _, s := range salas {
+		if salasUsadas[s.ID] {
+			continue
+		}
+		motivo := "as turmas compatíveis com a sala já foram atendidas"
+		if !salasCompativeis[s.ID] {
+			motivo = "nenhuma turma disponível é compatível com a capacidade, o tipo ou os equipamentos da sala"
+		}
+		naoAlocados = append(naoAlocados, models.RecursoNaoAlocado{Tipo: "sala", ID: s.ID, Nome: s.Numero, Motivo: motivo})
+	}
+
+	for _, p := range professores {
+		if professoresUsados[p.ID] {
+			continue
+		}
+		motivo := "as turmas compatíveis com o professor já foram atendidas"
+		if !professoresCompativeis[p.ID] {
+			motivo = "nenhuma turma disponível exige a disciplina do professor"
+		}
+		naoAlocados = append(naoAlocados, models.RecursoNaoAlocado{Tipo: "professor", ID: p.ID, Nome: p.Nome, Motivo: motivo})
+	}
+
+	return escolhidos, naoAlocados
+}
+
+// motivoTurmaSemOpcoes explica por que nenhuma combinação viável existe para a turma
+func motivoTurmaSemOpcoes(t models.Turma, salas, salasCompativeis []models.Sala, professoresCompativeis []models.Professor) string {
+	if len(salasCompativeis) == 0 {
+		for _, s := range salas {
+			if s.Capacidade >= t.QuantAlunos && tipoSalaAceito(s.Tipo, t) {
+				return fmt.Sprintf("nenhuma sala disponível tem os equipamentos exigidos pela turma: %s", descreverEquipamentos(t.EquipamentosExigidos))
+			}
+		}
+		for _, s := range salas {
+			if s.Capacidade >= t.QuantAlunos {
+				return fmt.Sprintf("nenhuma sala disponível do tipo %s comporta %d alunos", descreverTiposSala(t), t.QuantAlunos)
+			}
+		}
+		return fmt.Sprintf("nenhuma sala disponível comporta %d alunos", t.QuantAlunos)
+	}
+	if len(professoresCompativeis) == 0 {
+		return fmt.Sprintf("nenhum professor disponível leciona %s", t.Disciplina)
+	}
+	return "sem combinação viável de sala e professor"
+}
+
+// gerarToken gera um identificador aleatório para planos de alocação
+func gerarToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// salaAtendeTurma verifica se a sala comporta a turma, é de um tipo aceito por ela e tem os
+// equipamentos que ela exige
+func salaAtendeTurma(s models.Sala, t models.Turma) bool {
+	if s.Capacidade < t.QuantAlunos {
+		return false
+	}
+	return tipoSalaAceito(s.Tipo, t) && len(equipamentosFaltantes(s.Equipamentos, t.EquipamentosExigidos)) == 0
+}
+
+// tiposSalaDaTurma retorna o tipo exigido e os tipos aceitos pela turma, sem repetições
+func tiposSalaDaTurma(t models.Turma) []string {
+	var tipos []string
+	vistos := make(map[string]bool)
+	for _, tipo := range append([]string{t.TipoSala}, t.TiposSalaAceitos...) {
+		chave := normalizarTexto(tipo)
+		if chave == "" || vistos[chave] {
+			continue
+		}
+		vistos[chave] = true
+		tipos = append(tipos, strings.TrimSpace(tipo))
+	}
+	return tipos
+}
+
+// tipoSalaAceito verifica se uma sala do tipo informado atende a turma. Turmas sem
+// tipo exigido nem tipos aceitos podem usar qualquer sala.
+func tipoSalaAceito(tipo string, t models.Turma) bool {
+	tipos := tiposSalaDaTurma(t)
+	if len(tipos) == 0 {
+		return true
+	}
+	for _, aceito := range tipos {
+		if normalizarTexto(tipo) == normalizarTexto(aceito) {
+			return true
+		}
+	}
+	return false
+}
+
+// descreverTiposSala lista os tipos de sala que atendem a turma, como "Laboratório ou Sala comum"
+func descreverTiposSala(t models.Turma) string {
+	return strings.Join(tiposSalaDaTurma(t), " ou ")
+}
+
+// professorAtendeTurma verifica se o professor leciona a disciplina da turma. Se a turma tem
+// disciplina cadastrada, vale a associação do professor a ela; senão, compara-se o nome.
+func professorAtendeTurma(p models.Professor, t models.Turma) bool {
+	if t.DisciplinaID != nil && professorLecionaDisciplina(p, *t.DisciplinaID) {
+		return true
+	}
+	if t.Disciplina == "" {
+		return t.DisciplinaID == nil
+	}
+	return normalizarTexto(p.Disciplina) == normalizarTexto(t.Disciplina)
+}
+
+// pontuarCandidato calcula a adequação de uma combinação viável. Quanto maior, melhor:
+// salas com menos lugares ociosos são favorecidas.
+func pontuarCandidato(p models.Professor, s models.Sala, t models.Turma) int {
+	pontuacao := -(s.Capacidade - t.QuantAlunos)
+
+	// Evitar ocupar salas especiais com turmas que não as exigem
+	if len(tiposSalaDaTurma(t)) == 0 && s.Tipo != "" && normalizarTexto(s.Tipo) != "sala comum" {
+		pontuacao -= 50
+	}
+
+	// Preferir o tipo exigido pela turma aos tipos apenas aceitos
+	if t.TipoSala != "" && normalizarTexto(s.Tipo) != normalizarTexto(t.TipoSala) {
+		pontuacao -= 25
+	}
+
+	return pontuacao
+}
+
+// normalizarTexto remove acentos, espaços extras e diferenças de caixa para comparação de textos livres
+func normalizarTexto(texto string) string {
+	return strings.Join(strings.Fields(removedorAcentos.Replace(strings.ToLower(texto))), " ")
+}
+
+var removedorAcentos = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
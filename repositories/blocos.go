@@ -0,0 +1,471 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrBlocoInvalido indica que o campus, o bloco ou o tempo de deslocamento informados são inválidos
+var ErrBlocoInvalido = errors.New("dados do bloco inválidos")
+
+// CampusRepository gerencia operações de banco de dados para campi
+type CampusRepository struct {
+	DB *sql.DB
+}
+
+// NewCampusRepository cria um novo repositório de campi
+func NewCampusRepository(db *sql.DB) *CampusRepository {
+	return &CampusRepository{DB: db}
+}
+
+// BlocoRepository gerencia operações de banco de dados para blocos e os tempos de deslocamento entre eles
+type BlocoRepository struct {
+	DB *sql.DB
+}
+
+// NewBlocoRepository cria um novo repositório de blocos
+func NewBlocoRepository(db *sql.DB) *BlocoRepository {
+	return &BlocoRepository{DB: db}
+}
+
+// ===== Métodos do CampusRepository =====
+
+// GetAll retorna todos os campi
+func (r *CampusRepository) GetAll() ([]models.Campus, error) {
+	rows, err := r.DB.Query("SELECT id, nome FROM campi ORDER BY nome")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campi := []models.Campus{}
+	for rows.Next() {
+		var c models.Campus
+		if err := rows.Scan(&c.ID, &c.Nome); err != nil {
+			return nil, err
+		}
+		campi = append(campi, c)
+	}
+
+	return campi, rows.Err()
+}
+
+// GetByID retorna um campus pelo ID
+func (r *CampusRepository) GetByID(id int) (models.Campus, error) {
+	var c models.Campus
+	err := r.DB.QueryRow("SELECT id, nome FROM campi WHERE id = $1", id).Scan(&c.ID, &c.Nome)
+	if err != nil {
+		return models.Campus{}, err
+	}
+	return c, nil
+}
+
+// Create cria um novo campus
+func (r *CampusRepository) Create(c models.Campus) (models.Campus, error) {
+	c.Nome = strings.TrimSpace(c.Nome)
+	if c.Nome == "" {
+		return models.Campus{}, fmt.Errorf("%w: nome do campus é obrigatório", ErrBlocoInvalido)
+	}
+
+	err := r.DB.QueryRow("INSERT INTO campi (nome) VALUES ($1) RETURNING id", c.Nome).Scan(&c.ID)
+	if err != nil {
+		return models.Campus{}, traduzirErroBloco(err)
+	}
+
+	return c, nil
+}
+
+// Update atualiza um campus existente
+func (r *CampusRepository) Update(c models.Campus) error {
+	c.Nome = strings.TrimSpace(c.Nome)
+	if c.Nome == "" {
+		return fmt.Errorf("%w: nome do campus é obrigatório", ErrBlocoInvalido)
+	}
+
+	result, err := r.DB.Exec("UPDATE campi SET nome = $1 WHERE id = $2", c.Nome, c.ID)
+	if err != nil {
+		return traduzirErroBloco(err)
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// Delete remove um campus pelo ID; os blocos do campus ficam sem campus cadastrado
+func (r *CampusRepository) Delete(id int) error {
+	_, err := r.DB.Exec("DELETE FROM campi WHERE id = $1", id)
+	return err
+}
+
+// ===== Métodos do BlocoRepository =====
+
+// blocoSelectQuery é a consulta base de blocos
+const blocoSelectQuery = "SELECT id, nome, campus_id FROM blocos"
+
+// scanBloco lê uma linha da consulta base de blocos
+func scanBloco(row interface{ Scan(dest ...any) error }) (models.Bloco, error) {
+	var b models.Bloco
+	err := row.Scan(&b.ID, &b.Nome, &b.CampusID)
+	if err != nil {
+		return models.Bloco{}, err
+	}
+	return b, nil
+}
+
+// queryBlocos executa a consulta base de blocos com o filtro informado
+func queryBlocos(db executor, filtro string, args ...any) ([]models.Bloco, error) {
+	rows, err := db.Query(blocoSelectQuery+filtro+" ORDER BY nome", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocos := []models.Bloco{}
+	for rows.Next() {
+		b, err := scanBloco(rows)
+		if err != nil {
+			return nil, err
+		}
+		blocos = append(blocos, b)
+	}
+
+	return blocos, rows.Err()
+}
+
+// GetAll retorna todos os blocos
+func (r *BlocoRepository) GetAll() ([]models.Bloco, error) {
+	return queryBlocos(r.DB, "")
+}
+
+// GetByCampus retorna os blocos de um campus
+func (r *BlocoRepository) GetByCampus(campusID int) ([]models.Bloco, error) {
+	return queryBlocos(r.DB, " WHERE campus_id = $1", campusID)
+}
+
+// GetByID retorna um bloco pelo ID
+func (r *BlocoRepository) GetByID(id int) (models.Bloco, error) {
+	return scanBloco(r.DB.QueryRow(blocoSelectQuery+" WHERE id = $1", id))
+}
+
+// Create cria um novo bloco
+func (r *BlocoRepository) Create(b models.Bloco) (models.Bloco, error) {
+	b.Nome = strings.TrimSpace(b.Nome)
+	if b.Nome == "" {
+		return models.Bloco{}, fmt.Errorf("%w: nome do bloco é obrigatório", ErrBlocoInvalido)
+	}
+
+	err := r.DB.QueryRow("INSERT INTO blocos (nome, campus_id) VALUES ($1, $2) RETURNING id", b.Nome, b.CampusID).Scan(&b.ID)
+	if err != nil {
+		return models.Bloco{}, traduzirErroBloco(err)
+	}
+
+	return b, nil
+}
+
+// Update atualiza um bloco existente e o nome do bloco nas salas associadas a ele
+func (r *BlocoRepository) Update(b models.Bloco) error {
+	b.Nome = strings.TrimSpace(b.Nome)
+	if b.Nome == "" {
+		return fmt.Errorf("%w: nome do bloco é obrigatório", ErrBlocoInvalido)
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE blocos SET nome = $1, campus_id = $2 WHERE id = $3", b.Nome, b.CampusID, b.ID)
+	if err != nil {
+		return traduzirErroBloco(err)
+	}
+	if err := exigirLinhaAfetada(result); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE salas SET bloco = $1 WHERE bloco_id = $2", b.Nome, b.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete remove um bloco pelo ID, junto com os tempos de deslocamento dele; as salas do bloco
+// ficam sem bloco cadastrado
+func (r *BlocoRepository) Delete(id int) error {
+	_, err := r.DB.Exec("DELETE FROM blocos WHERE id = $1", id)
+	return err
+}
+
+// GetDeslocamentos retorna todos os tempos de deslocamento cadastrados entre blocos
+func (r *BlocoRepository) GetDeslocamentos() ([]models.Deslocamento, error) {
+	rows, err := r.DB.Query(`SELECT bloco_origem_id, bloco_destino_id, minutos FROM deslocamentos_blocos
+		ORDER BY bloco_origem_id, bloco_destino_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deslocamentos := []models.Deslocamento{}
+	for rows.Next() {
+		var d models.Deslocamento
+		if err := rows.Scan(&d.BlocoOrigemID, &d.BlocoDestinoID, &d.Minutos); err != nil {
+			return nil, err
+		}
+		deslocamentos = append(deslocamentos, d)
+	}
+
+	return deslocamentos, rows.Err()
+}
+
+// SalvarDeslocamentos grava os tempos de deslocamento informados, substituindo os já cadastrados
+// para os mesmos pares de blocos. Como o tempo vale nos dois sentidos, cada par é gravado com o
+// menor ID como origem.
+func (r *BlocoRepository) SalvarDeslocamentos(deslocamentos []models.Deslocamento) error {
+	for _, d := range deslocamentos {
+		if d.BlocoOrigemID == d.BlocoDestinoID {
+			return fmt.Errorf("%w: o bloco de origem e o de destino devem ser diferentes", ErrBlocoInvalido)
+		}
+		if d.Minutos < 0 {
+			return fmt.Errorf("%w: o tempo de deslocamento não pode ser negativo", ErrBlocoInvalido)
+		}
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range deslocamentos {
+		par := parDeBlocos(d.BlocoOrigemID, d.BlocoDestinoID)
+		_, err := tx.Exec(`INSERT INTO deslocamentos_blocos (bloco_origem_id, bloco_destino_id, minutos) VALUES ($1, $2, $3)
+			ON CONFLICT (bloco_origem_id, bloco_destino_id) DO UPDATE SET minutos = EXCLUDED.minutos`, par[0], par[1], d.Minutos)
+		if err != nil {
+			return traduzirErroBloco(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RemoverDeslocamento remove o tempo de deslocamento cadastrado entre dois blocos
+func (r *BlocoRepository) RemoverDeslocamento(origemID, destinoID int) error {
+	par := parDeBlocos(origemID, destinoID)
+	result, err := r.DB.Exec("DELETE FROM deslocamentos_blocos WHERE bloco_origem_id = $1 AND bloco_destino_id = $2", par[0], par[1])
+	if err != nil {
+		return err
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// parDeBlocos ordena um par de blocos, já que o tempo de deslocamento vale nos dois sentidos
+func parDeBlocos(a, b int) [2]int {
+	return [2]int{min(a, b), max(a, b)}
+}
+
+// temposDeslocamento retorna os tempos de deslocamento cadastrados, indexados pelo par de blocos
+func temposDeslocamento(db executor) (map[[2]int]int, error) {
+	rows, err := db.Query("SELECT bloco_origem_id, bloco_destino_id, minutos FROM deslocamentos_blocos")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tempos := make(map[[2]int]int)
+	for rows.Next() {
+		var origem, destino, minutos int
+		if err := rows.Scan(&origem, &destino, &minutos); err != nil {
+			return nil, err
+		}
+		tempos[parDeBlocos(origem, destino)] = minutos
+	}
+
+	return tempos, rows.Err()
+}
+
+// aulaVizinha é uma aula do professor ou da turma no mesmo dia de uma alocação, com o bloco da sala
+type aulaVizinha struct {
+	models.HorarioAula
+	AlocacaoID  int
+	BlocoID     *int
+	professorID int
+	turmaID     int
+}
+
+// aulasVizinhas retorna, para o professor e para a turma da alocação, a aula imediatamente anterior
+// e a imediatamente posterior no mesmo dia do período letivo, considerando apenas as alocações com
+// aula em alguma data em comum. Uma aula do professor com a própria turma aparece uma vez.
+func aulasVizinhas(db executor, a models.Alocacao) ([]aulaVizinha, error) {
+	periodo, err := getPeriodoLetivoByID(db, a.PeriodoLetivoID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT a.id, a.professor_id, a.turma_id, a.dia_semana, a.horario_inicio, a.horario_fim, s.bloco_id,
+		a.intervalo_semanas, a.data_inicio, a.data_fim, a.semanas
+		FROM alocacoes a JOIN salas s ON s.id = a.sala_id
+		WHERE a.periodo_letivo_id = $5 AND a.dia_semana = $1 AND a.id <> $2 AND (a.professor_id = $3 OR a.turma_id = $4)
+	`, a.DiaSemana, a.ID, a.ProfessorID, a.TurmaID, a.PeriodoLetivoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aulas []aulaVizinha
+	for rows.Next() {
+		var v aulaVizinha
+		var intervalo int
+		var inicio, fim *models.Data
+		var semanas pq.Int64Array
+		if err := rows.Scan(&v.AlocacaoID, &v.professorID, &v.turmaID, &v.DiaSemana, &v.HorarioInicio, &v.HorarioFim, &v.BlocoID,
+			&intervalo, &inicio, &fim, &semanas); err != nil {
+			return nil, err
+		}
+
+		outra := models.Alocacao{DiaSemana: v.DiaSemana, Recorrencia: recorrenciaDasColunas(intervalo, inicio, fim, semanas)}
+		if acontecemJuntas(a, outra, periodo) {
+			aulas = append(aulas, v)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return escolherVizinhas(a, aulas), nil
+}
+
+// escolherVizinhas retorna, entre as aulas do mesmo dia, a imediatamente anterior e a imediatamente
+// posterior à alocação para o professor e para a turma dela, sem repetir aulas
+func escolherVizinhas(a models.Alocacao, aulas []aulaVizinha) []aulaVizinha {
+	// Aula anterior e posterior mais próximas, por professor (índice 0) e por turma (índice 1)
+	var anteriores, posteriores [2]*aulaVizinha
+	for _, v := range aulas {
+		for i, participa := range []bool{v.professorID == a.ProfessorID, v.turmaID == a.TurmaID} {
+			if !participa {
+				continue
+			}
+			switch {
+			case v.HorarioFim <= a.HorarioInicio:
+				if anteriores[i] == nil || v.HorarioFim > anteriores[i].HorarioFim {
+					anteriores[i] = &v
+				}
+			case v.HorarioInicio >= a.HorarioFim:
+				if posteriores[i] == nil || v.HorarioInicio < posteriores[i].HorarioInicio {
+					posteriores[i] = &v
+				}
+			}
+		}
+	}
+
+	var vizinhas []aulaVizinha
+	vistas := make(map[int]bool)
+	for _, v := range append(anteriores[:], posteriores[:]...) {
+		if v != nil && !vistas[v.AlocacaoID] {
+			vistas[v.AlocacaoID] = true
+			vizinhas = append(vizinhas, *v)
+		}
+	}
+	return vizinhas
+}
+
+// analisarDeslocamentos compara o bloco da sala da alocação com o das aulas vizinhas do professor
+// e da turma no mesmo dia. Quando o tempo de deslocamento entre os blocos é maior que o intervalo
+// entre as aulas, a alocação é recusada; quando cabe no intervalo, gera apenas um aviso. Pares de
+// blocos sem tempo cadastrado não são verificados.
+func analisarDeslocamentos(db executor, a models.Alocacao, sala models.Sala) (violacoes, avisos []models.Violacao, err error) {
+	if sala.BlocoID == nil {
+		return nil, nil, nil
+	}
+
+	vizinhas, err := aulasVizinhas(db, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	return compararDeslocamentos(db, a, sala, vizinhas)
+}
+
+// compararDeslocamentos compara o bloco da sala da aula com o das aulas vizinhas informadas e
+// separa as violações, quando o deslocamento não cabe no intervalo, dos avisos
+func compararDeslocamentos(db executor, a models.Alocacao, sala models.Sala, vizinhas []aulaVizinha) (violacoes, avisos []models.Violacao, err error) {
+	if sala.BlocoID == nil || len(vizinhas) == 0 {
+		return nil, nil, nil
+	}
+
+	tempos, err := temposDeslocamento(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, v := range vizinhas {
+		if v.BlocoID == nil || *v.BlocoID == *sala.BlocoID {
+			continue
+		}
+		minutos, ok := tempos[parDeBlocos(*v.BlocoID, *sala.BlocoID)]
+		if !ok || minutos == 0 {
+			continue
+		}
+
+		intervalo := a.HorarioInicio.Minutos() - v.HorarioFim.Minutos()
+		if v.HorarioInicio >= a.HorarioFim {
+			intervalo = v.HorarioInicio.Minutos() - a.HorarioFim.Minutos()
+		}
+
+		detalhes := map[string]any{
+			"alocacao_id":       v.AlocacaoID,
+			"bloco_id":          *sala.BlocoID,
+			"bloco_vizinho_id":  *v.BlocoID,
+			"minutos":           minutos,
+			"intervalo_minutos": intervalo,
+		}
+		if minutos > intervalo {
+			violacoes = append(violacoes, models.Violacao{
+				Regra: "deslocamento_insuficiente",
+				Mensagem: fmt.Sprintf("a aula das %s às %s fica a %d minutos de deslocamento da sala %s, mas o intervalo é de %d minutos",
+					v.HorarioInicio, v.HorarioFim, minutos, sala.Numero, intervalo),
+				Detalhes: detalhes,
+			})
+		} else {
+			avisos = append(avisos, models.Violacao{
+				Regra: "deslocamento_entre_blocos",
+				Mensagem: fmt.Sprintf("a aula das %s às %s é em outro bloco, a %d minutos de deslocamento da sala %s",
+					v.HorarioInicio, v.HorarioFim, minutos, sala.Numero),
+				Detalhes: detalhes,
+			})
+		}
+	}
+
+	return violacoes, avisos, nil
+}
+
+// avisosDeslocamento retorna os avisos de deslocamento entre blocos de uma alocação já gravada
+func avisosDeslocamento(db executor, a models.Alocacao) ([]models.Violacao, error) {
+	_, avisos, err := analisarDeslocamentos(db, a, a.Sala)
+	return avisos, err
+}
+
+// traduzirErroBloco converte nomes repetidos e referências a campi ou blocos inexistentes em ErrBlocoInvalido
+func traduzirErroBloco(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch {
+	case pqErr.Code == codigoViolacaoUnicidade && pqErr.Constraint == "campi_nome_unico":
+		return fmt.Errorf("%w: já existe um campus com este nome", ErrBlocoInvalido)
+	case pqErr.Code == codigoViolacaoUnicidade && pqErr.Constraint == "blocos_nome_unico":
+		return fmt.Errorf("%w: já existe um bloco com este nome", ErrBlocoInvalido)
+	case pqErr.Code == codigoViolacaoChaveEstrangeira && strings.Contains(pqErr.Constraint, "campus_id"):
+		return fmt.Errorf("%w: campus não encontrado", ErrBlocoInvalido)
+	case pqErr.Code == codigoViolacaoChaveEstrangeira && strings.Contains(pqErr.Constraint, "bloco"):
+		return fmt.Errorf("%w: bloco não encontrado", ErrBlocoInvalido)
+	}
+	return err
+}
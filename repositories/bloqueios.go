@@ -0,0 +1,288 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrBloqueioInvalido indica que o período de bloqueio informado é inválido
+var ErrBloqueioInvalido = errors.New("dados do bloqueio inválidos")
+
+// BloqueioSalaRepository gerencia operações de banco de dados para os bloqueios de salas
+type BloqueioSalaRepository struct {
+	DB *sql.DB
+}
+
+// NewBloqueioSalaRepository cria um novo repositório de bloqueios de salas
+func NewBloqueioSalaRepository(db *sql.DB) *BloqueioSalaRepository {
+	return &BloqueioSalaRepository{DB: db}
+}
+
+// bloqueioSelectQuery é a consulta base dos bloqueios de salas
+const bloqueioSelectQuery = `
+	SELECT id, sala_id, COALESCE(dia_semana, ''), horario_inicio, horario_fim, data_inicio, data_fim, motivo
+	FROM bloqueios_sala
+`
+
+// scanBloqueio lê uma linha da consulta base de bloqueios
+func scanBloqueio(row interface{ Scan(dest ...any) error }) (models.BloqueioSala, error) {
+	var b models.BloqueioSala
+	var dia string
+	err := row.Scan(&b.ID, &b.SalaID, &dia, &b.HorarioInicio, &b.HorarioFim, &b.DataInicio, &b.DataFim, &b.Motivo)
+	if err != nil {
+		return models.BloqueioSala{}, err
+	}
+
+	if dia != "" {
+		if b.DiaSemana, err = models.ParseDiaSemana(dia); err != nil {
+			return models.BloqueioSala{}, err
+		}
+	}
+	return b, nil
+}
+
+// queryBloqueios executa a consulta base de bloqueios com o filtro informado
+func queryBloqueios(db executor, filtro string, args ...any) ([]models.BloqueioSala, error) {
+	rows, err := db.Query(bloqueioSelectQuery+filtro+" ORDER BY sala_id, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bloqueios := []models.BloqueioSala{}
+	for rows.Next() {
+		b, err := scanBloqueio(rows)
+		if err != nil {
+			return nil, err
+		}
+		bloqueios = append(bloqueios, b)
+	}
+
+	return bloqueios, rows.Err()
+}
+
+// GetBySala retorna os bloqueios de uma sala
+func (r *BloqueioSalaRepository) GetBySala(salaID int) ([]models.BloqueioSala, error) {
+	return queryBloqueios(r.DB, " WHERE sala_id = $1", salaID)
+}
+
+// GetByID retorna um bloqueio de uma sala
+func (r *BloqueioSalaRepository) GetByID(salaID, id int) (models.BloqueioSala, error) {
+	return scanBloqueio(r.DB.QueryRow(bloqueioSelectQuery+" WHERE id = $1 AND sala_id = $2", id, salaID))
+}
+
+// Create cria um novo bloqueio e marca as alocações já existentes que caem nele
+func (r *BloqueioSalaRepository) Create(b models.BloqueioSala) (models.BloqueioSala, error) {
+	if err := validarBloqueio(b); err != nil {
+		return models.BloqueioSala{}, err
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return models.BloqueioSala{}, err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO bloqueios_sala (sala_id, dia_semana, horario_inicio, horario_fim, data_inicio, data_fim, motivo)
+			VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7) RETURNING id`
+
+	err = tx.QueryRow(query, b.SalaID, string(b.DiaSemana), b.HorarioInicio, b.HorarioFim, b.DataInicio, b.DataFim, b.Motivo).Scan(&b.ID)
+	if err != nil {
+		return models.BloqueioSala{}, err
+	}
+
+	b.AlocacoesAfetadas, err = marcarAlocacoesBloqueadas(tx, b)
+	if err != nil {
+		return models.BloqueioSala{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.BloqueioSala{}, err
+	}
+	return b, nil
+}
+
+// Update atualiza um bloqueio existente e refaz a marcação das alocações que caem nele
+func (r *BloqueioSalaRepository) Update(b models.BloqueioSala) (models.BloqueioSala, error) {
+	if err := validarBloqueio(b); err != nil {
+		return models.BloqueioSala{}, err
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return models.BloqueioSala{}, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE bloqueios_sala SET dia_semana = NULLIF($1, ''), horario_inicio = $2, horario_fim = $3,
+			data_inicio = $4, data_fim = $5, motivo = $6 WHERE id = $7 AND sala_id = $8`
+
+	result, err := tx.Exec(query, string(b.DiaSemana), b.HorarioInicio, b.HorarioFim, b.DataInicio, b.DataFim, b.Motivo, b.ID, b.SalaID)
+	if err != nil {
+		return models.BloqueioSala{}, err
+	}
+	if err := exigirLinhaAfetada(result); err != nil {
+		return models.BloqueioSala{}, err
+	}
+
+	_, err = tx.Exec("UPDATE alocacoes SET bloqueio_sala_id = NULL WHERE bloqueio_sala_id = $1", b.ID)
+	if err != nil {
+		return models.BloqueioSala{}, err
+	}
+
+	b.AlocacoesAfetadas, err = marcarAlocacoesBloqueadas(tx, b)
+	if err != nil {
+		return models.BloqueioSala{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.BloqueioSala{}, err
+	}
+	return b, nil
+}
+
+// Delete remove um bloqueio de uma sala; as alocações marcadas por ele deixam de estar bloqueadas
+func (r *BloqueioSalaRepository) Delete(salaID, id int) error {
+	result, err := r.DB.Exec("DELETE FROM bloqueios_sala WHERE id = $1 AND sala_id = $2", id, salaID)
+	if err != nil {
+		return err
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// validarBloqueio verifica se o bloqueio informa um período válido e o motivo
+func validarBloqueio(b models.BloqueioSala) error {
+	if b.Motivo == "" {
+		return fmt.Errorf("%w: motivo é obrigatório", ErrBloqueioInvalido)
+	}
+	if b.DiaSemana == "" && b.DataInicio == nil {
+		return fmt.Errorf("%w: informe dia_semana ou data_inicio e data_fim", ErrBloqueioInvalido)
+	}
+	if (b.DataInicio == nil) != (b.DataFim == nil) {
+		return fmt.Errorf("%w: data_inicio e data_fim devem ser informadas juntas", ErrBloqueioInvalido)
+	}
+	if b.DataInicio != nil && b.DataFim.Before(b.DataInicio.Time) {
+		return fmt.Errorf("%w: data_fim (%s) não pode ser anterior a data_inicio (%s)", ErrBloqueioInvalido, b.DataFim, b.DataInicio)
+	}
+	if (b.HorarioInicio == nil) != (b.HorarioFim == nil) {
+		return fmt.Errorf("%w: horario_inicio e horario_fim devem ser informados juntos", ErrBloqueioInvalido)
+	}
+	if b.HorarioInicio != nil {
+		if err := models.ValidarIntervalo(*b.HorarioInicio, *b.HorarioFim); err != nil {
+			return fmt.Errorf("%w: %v", ErrBloqueioInvalido, err)
+		}
+	}
+	return nil
+}
+
+// bloqueiosPorSala retorna os bloqueios de todas as salas, agrupados por sala
+func bloqueiosPorSala(db executor) (map[int][]models.BloqueioSala, error) {
+	bloqueios, err := queryBloqueios(db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	porSala := make(map[int][]models.BloqueioSala)
+	for _, b := range bloqueios {
+		porSala[b.SalaID] = append(porSala[b.SalaID], b)
+	}
+	return porSala, nil
+}
+
+// bloqueioAfeta verifica se o bloqueio atinge uma aula semanal no dia e horário informados, dada
+// entre as datas desde e ate. Um bloqueio por período de datas atinge a aula se alguma data em
+// comum com esse intervalo cai no dia da semana da aula.
+func bloqueioAfeta(b models.BloqueioSala, dia models.DiaSemana, inicio, fim models.Horario, desde, ate models.Data) bool {
+	if b.HorarioInicio != nil && (*b.HorarioInicio >= fim || inicio >= *b.HorarioFim) {
+		return false
+	}
+	if b.DiaSemana != "" && b.DiaSemana != dia {
+		return false
+	}
+	if b.DataInicio == nil {
+		return true
+	}
+
+	primeira, ultima := *b.DataInicio, *b.DataFim
+	if primeira.Before(desde.Time) {
+		primeira = desde
+	}
+	if ultima.After(ate.Time) {
+		ultima = ate
+	}
+	for d, n := primeira, 0; !d.After(ultima.Time) && n < 7; d, n = d.AdicionarDias(1), n+1 {
+		if d.DiaSemana() == dia {
+			return true
+		}
+	}
+	return false
+}
+
+// bloqueioQueAfeta retorna o primeiro bloqueio da lista que atinge alguma aula da alocação que
+// ainda vai acontecer no período letivo, se houver
+func bloqueioQueAfeta(bloqueios []models.BloqueioSala, a models.Alocacao, periodo models.PeriodoLetivo) (models.BloqueioSala, bool) {
+	hoje := models.Hoje()
+	for _, b := range bloqueios {
+		if bloqueioAfetaAlocacao(b, a, periodo, hoje) {
+			return b, true
+		}
+	}
+	return models.BloqueioSala{}, false
+}
+
+// bloqueioAfetaAlocacao verifica se o bloqueio atinge alguma aula da alocação que ainda vai
+// acontecer, nas datas em que ela tem aula no período letivo de acordo com a sua recorrência
+func bloqueioAfetaAlocacao(b models.BloqueioSala, a models.Alocacao, p models.PeriodoLetivo, hoje models.Data) bool {
+	for _, d := range datasDaAlocacao(a, p) {
+		if !d.Before(hoje.Time) && bloqueioAfeta(b, a.DiaSemana, a.HorarioInicio, a.HorarioFim, d, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// descreverBloqueio descreve o período e o motivo de um bloqueio, como "Segunda 08:00-12:00 (reforma)"
+func descreverBloqueio(b models.BloqueioSala) string {
+	var periodo string
+	if b.DataInicio != nil {
+		periodo = fmt.Sprintf("de %s a %s", b.DataInicio, b.DataFim)
+	}
+	if b.DiaSemana != "" {
+		periodo = fmt.Sprint(b.DiaSemana, " ", periodo)
+	}
+	if b.HorarioInicio != nil {
+		periodo = fmt.Sprintf("%s %s-%s", periodo, b.HorarioInicio, b.HorarioFim)
+	}
+	return fmt.Sprintf("%s (%s)", periodo, b.Motivo)
+}
+
+// marcarAlocacoesBloqueadas marca as alocações da sala que caem no bloqueio e retorna seus IDs
+func marcarAlocacoesBloqueadas(db executor, b models.BloqueioSala) ([]int, error) {
+	alocacoes, err := queryAlocacoes(db, " WHERE a.sala_id = $1", b.SalaID)
+	if err != nil {
+		return nil, err
+	}
+
+	periodos, err := periodosLetivosPorID(db)
+	if err != nil {
+		return nil, err
+	}
+
+	hoje := models.Hoje()
+	afetadas := []int{}
+	for _, a := range alocacoes {
+		if !bloqueioAfetaAlocacao(b, a, periodos[a.PeriodoLetivoID], hoje) {
+			continue
+		}
+
+		_, err = db.Exec("UPDATE alocacoes SET bloqueio_sala_id = $1 WHERE id = $2", b.ID, a.ID)
+		if err != nil {
+			return nil, err
+		}
+		afetadas = append(afetadas, a.ID)
+	}
+	return afetadas, nil
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrDiaNaoLetivoInvalido indica que o dia não letivo informado é inválido
+var ErrDiaNaoLetivoInvalido = errors.New("dados do dia não letivo inválidos")
+
+// DiaNaoLetivoRepository gerencia operações de banco de dados para o calendário de dias não letivos
+type DiaNaoLetivoRepository struct {
+	DB *sql.DB
+}
+
+// NewDiaNaoLetivoRepository cria um novo repositório de dias não letivos
+func NewDiaNaoLetivoRepository(db *sql.DB) *DiaNaoLetivoRepository {
+	return &DiaNaoLetivoRepository{DB: db}
+}
+
+// diaNaoLetivoSelectQuery é a consulta base dos dias não letivos
+const diaNaoLetivoSelectQuery = "SELECT id, data, descricao, tipo FROM dias_nao_letivos"
+
+// queryDiasNaoLetivos executa a consulta base de dias não letivos com o filtro informado, em ordem de data
+func queryDiasNaoLetivos(db executor, filtro string, args ...any) ([]models.DiaNaoLetivo, error) {
+	rows, err := db.Query(diaNaoLetivoSelectQuery+filtro+" ORDER BY data", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dias := []models.DiaNaoLetivo{}
+	for rows.Next() {
+		var d models.DiaNaoLetivo
+		if err := rows.Scan(&d.ID, &d.Data, &d.Descricao, &d.Tipo); err != nil {
+			return nil, err
+		}
+		dias = append(dias, d)
+	}
+
+	return dias, rows.Err()
+}
+
+// diasNaoLetivosEntre retorna os dias não letivos entre as datas informadas, indexados pela data no formato AAAA-MM-DD
+func diasNaoLetivosEntre(db executor, inicio, fim models.Data) (map[string]models.DiaNaoLetivo, error) {
+	dias, err := queryDiasNaoLetivos(db, " WHERE data BETWEEN $1 AND $2", inicio, fim)
+	if err != nil {
+		return nil, err
+	}
+
+	porData := make(map[string]models.DiaNaoLetivo, len(dias))
+	for _, d := range dias {
+		porData[d.Data.String()] = d
+	}
+	return porData, nil
+}
+
+// GetAll retorna os dias não letivos do período letivo informado ou, com ID zero, de todos os períodos
+func (r *DiaNaoLetivoRepository) GetAll(periodoLetivoID int) ([]models.DiaNaoLetivo, error) {
+	if periodoLetivoID == 0 {
+		return queryDiasNaoLetivos(r.DB, "")
+	}
+
+	periodo, err := resolverPeriodoLetivo(r.DB, periodoLetivoID)
+	if err != nil {
+		return nil, err
+	}
+	return queryDiasNaoLetivos(r.DB, " WHERE data BETWEEN $1 AND $2", periodo.DataInicio, periodo.DataFim)
+}
+
+// GetByID retorna um dia não letivo pelo ID
+func (r *DiaNaoLetivoRepository) GetByID(id int) (models.DiaNaoLetivo, error) {
+	var d models.DiaNaoLetivo
+	err := r.DB.QueryRow(diaNaoLetivoSelectQuery+" WHERE id = $1", id).Scan(&d.ID, &d.Data, &d.Descricao, &d.Tipo)
+	return d, err
+}
+
+// Create cadastra um novo dia não letivo
+func (r *DiaNaoLetivoRepository) Create(d models.DiaNaoLetivo) (models.DiaNaoLetivo, error) {
+	if err := validarDiaNaoLetivo(&d); err != nil {
+		return models.DiaNaoLetivo{}, err
+	}
+
+	query := `INSERT INTO dias_nao_letivos (data, descricao, tipo) VALUES ($1, $2, $3) RETURNING id`
+
+	err := r.DB.QueryRow(query, d.Data, d.Descricao, d.Tipo).Scan(&d.ID)
+	if err != nil {
+		return models.DiaNaoLetivo{}, traduzirErroDiaNaoLetivo(d, err)
+	}
+
+	return d, nil
+}
+
+// Update atualiza um dia não letivo existente
+func (r *DiaNaoLetivoRepository) Update(d models.DiaNaoLetivo) error {
+	if err := validarDiaNaoLetivo(&d); err != nil {
+		return err
+	}
+
+	query := `UPDATE dias_nao_letivos SET data = $1, descricao = $2, tipo = $3 WHERE id = $4`
+
+	result, err := r.DB.Exec(query, d.Data, d.Descricao, d.Tipo, d.ID)
+	if err != nil {
+		return traduzirErroDiaNaoLetivo(d, err)
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// Delete remove um dia não letivo pelo ID
+func (r *DiaNaoLetivoRepository) Delete(id int) error {
+	_, err := r.DB.Exec("DELETE FROM dias_nao_letivos WHERE id = $1", id)
+	return err
+}
+
+// validarDiaNaoLetivo verifica se o dia não letivo tem data, descrição e um tipo conhecido
+func validarDiaNaoLetivo(d *models.DiaNaoLetivo) error {
+	if d.Data.IsZero() {
+		return fmt.Errorf("%w: data é obrigatória", ErrDiaNaoLetivoInvalido)
+	}
+
+	d.Descricao = strings.TrimSpace(d.Descricao)
+	if d.Descricao == "" {
+		return fmt.Errorf("%w: descricao é obrigatória", ErrDiaNaoLetivoInvalido)
+	}
+
+	d.Tipo = normalizarTipo(d.Tipo)
+	if d.Tipo == "" {
+		d.Tipo = models.DiaNaoLetivoFeriado
+	}
+	if d.Tipo != models.DiaNaoLetivoFeriado && d.Tipo != models.DiaNaoLetivoSemAula {
+		return fmt.Errorf("%w: tipo deve ser %q ou %q", ErrDiaNaoLetivoInvalido, models.DiaNaoLetivoFeriado, models.DiaNaoLetivoSemAula)
+	}
+	return nil
+}
+
+// traduzirErroDiaNaoLetivo converte o cadastro de dois dias não letivos na mesma data em ErrDiaNaoLetivoInvalido
+func traduzirErroDiaNaoLetivo(d models.DiaNaoLetivo, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == codigoViolacaoUnicidade && pqErr.Constraint == "dias_nao_letivos_data_unica" {
+		return fmt.Errorf("%w: já existe um dia não letivo em %s", ErrDiaNaoLetivoInvalido, d.Data)
+	}
+	return err
+}
+
+// normalizarTipo normaliza um tipo informado como "Sem aula" ou "sem_aula" para a forma gravada, "sem_aula"
+func normalizarTipo(tipo string) string {
+	return strings.ReplaceAll(normalizarTexto(tipo), " ", "_")
+}
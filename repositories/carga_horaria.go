@@ -0,0 +1,264 @@
+package repositories
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// intervaloMaximoConsecutivo é o maior intervalo, em minutos, entre duas aulas do mesmo dia
+// para que sejam contadas como consecutivas
+const intervaloMaximoConsecutivo = 15
+
+// minutosPorDia é a quantidade de minutos em um dia
+const minutosPorDia = 24 * 60
+
+// aulaProfessor é o horário de uma aula semanal do professor com as semanas do período letivo em
+// que ela acontece. Sem semanas, a aula acontece em todas as semanas do período.
+type aulaProfessor struct {
+	models.HorarioAula
+	semanas map[int]bool
+}
+
+// semanasDaAlocacao retorna as semanas do período letivo em que a alocação tem aula, ou nil se ela
+// não tem regra de recorrência e acontece toda semana
+func semanasDaAlocacao(a models.Alocacao, p models.PeriodoLetivo) map[int]bool {
+	if a.Recorrencia == nil {
+		return nil
+	}
+
+	semanas := make(map[int]bool)
+	for _, d := range datasDaAlocacao(a, p) {
+		semanas[semanaDoPeriodo(p, d)] = true
+	}
+	return semanas
+}
+
+// aulasDoPeriodo lê as aulas das alocações do período letivo que atendem ao filtro, agrupadas por professor
+func aulasDoPeriodo(db executor, p models.PeriodoLetivo, filtro string, args ...any) (map[int][]aulaProfessor, error) {
+	rows, err := db.Query(`
+		SELECT professor_id, dia_semana, horario_inicio, horario_fim, intervalo_semanas, data_inicio, data_fim, semanas
+		FROM alocacoes WHERE periodo_letivo_id = $1`+filtro, append([]any{p.ID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	aulas := make(map[int][]aulaProfessor)
+	for rows.Next() {
+		var a models.Alocacao
+		var intervalo int
+		var inicio, fim *models.Data
+		var semanas pq.Int64Array
+		if err := rows.Scan(&a.ProfessorID, &a.DiaSemana, &a.HorarioInicio, &a.HorarioFim, &intervalo, &inicio, &fim, &semanas); err != nil {
+			return nil, err
+		}
+		a.Recorrencia = recorrenciaDasColunas(intervalo, inicio, fim, semanas)
+
+		aulas[a.ProfessorID] = append(aulas[a.ProfessorID], aulaProfessor{
+			HorarioAula: models.HorarioAula{DiaSemana: a.DiaSemana, HorarioInicio: a.HorarioInicio, HorarioFim: a.HorarioFim},
+			semanas:     semanasDaAlocacao(a, p),
+		})
+	}
+
+	return aulas, rows.Err()
+}
+
+// aulasPorProfessor retorna as aulas das alocações de todos os professores no período letivo, agrupadas por professor
+func aulasPorProfessor(db executor, p models.PeriodoLetivo) (map[int][]aulaProfessor, error) {
+	return aulasDoPeriodo(db, p, "")
+}
+
+// aulasDoProfessor retorna as aulas das alocações de um professor no período letivo, sem a alocação informada
+func aulasDoProfessor(db executor, professorID int, p models.PeriodoLetivo, excluirAlocacaoID int) ([]aulaProfessor, error) {
+	aulas, err := aulasDoPeriodo(db, p, " AND professor_id = $2 AND id <> $3", professorID, excluirAlocacaoID)
+	return aulas[professorID], err
+}
+
+// violacoesCargaNasSemanas verifica os limites de carga horária do professor em cada semana do
+// período letivo em que a nova aula acontece, contando apenas as aulas que acontecem nessa mesma
+// semana: duas aulas quinzenais em semanas alternadas não se somam. As violações retornadas são as
+// da primeira semana que ultrapassa algum limite, com a data de início dessa semana nos detalhes.
+func violacoesCargaNasSemanas(p models.Professor, aulas []aulaProfessor, nova aulaProfessor, periodo models.PeriodoLetivo) []models.Violacao {
+	// Sem nenhuma regra de recorrência, todas as semanas têm as mesmas aulas
+	semanal := nova.semanas == nil && !slices.ContainsFunc(aulas, func(a aulaProfessor) bool { return a.semanas != nil })
+	if semanal {
+		return violacoesCargaProfessor(p, horariosDasAulas(aulas, 0), nova.HorarioAula)
+	}
+
+	primeiraSegunda := periodo.DataInicio.AdicionarDias(1 - periodo.DataInicio.DiaSemana().Numero())
+	for semana := 1; semana <= semanaDoPeriodo(periodo, periodo.DataFim); semana++ {
+		if nova.semanas != nil && !nova.semanas[semana] {
+			continue
+		}
+
+		violacoes := violacoesCargaProfessor(p, horariosDasAulas(aulas, semana), nova.HorarioAula)
+		if len(violacoes) == 0 {
+			continue
+		}
+		for _, v := range violacoes {
+			v.Detalhes["semana_inicio"] = primeiraSegunda.AdicionarDias(7 * (semana - 1))
+		}
+		return violacoes
+	}
+	return nil
+}
+
+// horariosDasAulas retorna os horários das aulas que acontecem na semana do período letivo
+// informada; com semana zero, retorna todas
+func horariosDasAulas(aulas []aulaProfessor, semana int) []models.HorarioAula {
+	var horarios []models.HorarioAula
+	for _, a := range aulas {
+		if semana == 0 || a.semanas == nil || a.semanas[semana] {
+			horarios = append(horarios, a.HorarioAula)
+		}
+	}
+	return horarios
+}
+
+// violacoesCargaProfessor verifica se o professor, com as aulas que já tem na semana, pode receber
+// a nova aula sem ultrapassar os limites de carga horária semanal, diária e consecutiva e sem
+// reduzir o descanso entre o fim de um dia e o início do seguinte abaixo do mínimo
+func violacoesCargaProfessor(p models.Professor, aulas []models.HorarioAula, nova models.HorarioAula) []models.Violacao {
+	var violacoes []models.Violacao
+
+	duracao := nova.HorarioFim.Minutos() - nova.HorarioInicio.Minutos()
+	semanal, diaria := duracao, duracao
+	for _, a := range aulas {
+		semanal += a.HorarioFim.Minutos() - a.HorarioInicio.Minutos()
+		if a.DiaSemana == nova.DiaSemana {
+			diaria += a.HorarioFim.Minutos() - a.HorarioInicio.Minutos()
+		}
+	}
+
+	if limite := horasEmMinutos(p.MaxHorasSemanais); limite > 0 && semanal > limite {
+		violacoes = append(violacoes, models.Violacao{
+			Regra:    "carga_semanal_excedida",
+			Mensagem: fmt.Sprintf("o professor %s passaria a ter %s de aula na semana, acima do limite de %s", p.Nome, formatarHoras(semanal), formatarHoras(limite)),
+			Detalhes: map[string]any{"limite_horas": p.MaxHorasSemanais, "horas": minutosEmHoras(semanal)},
+		})
+	}
+
+	if limite := horasEmMinutos(p.MaxHorasDiarias); limite > 0 && diaria > limite {
+		violacoes = append(violacoes, models.Violacao{
+			Regra:    "carga_diaria_excedida",
+			Mensagem: fmt.Sprintf("o professor %s passaria a ter %s de aula em %s, acima do limite de %s", p.Nome, formatarHoras(diaria), nova.DiaSemana, formatarHoras(limite)),
+			Detalhes: map[string]any{"limite_horas": p.MaxHorasDiarias, "horas": minutosEmHoras(diaria), "dia_semana": nova.DiaSemana},
+		})
+	}
+
+	if limite := horasEmMinutos(p.MaxHorasConsecutivas); limite > 0 {
+		inicio, fim := blocoConsecutivo(aulas, nova)
+		if consecutivas := fim.Minutos() - inicio.Minutos(); consecutivas > limite {
+			violacoes = append(violacoes, models.Violacao{
+				Regra:    "horas_consecutivas_excedidas",
+				Mensagem: fmt.Sprintf("o professor %s passaria a dar %s de aula seguidas em %s (%s-%s), acima do limite de %s", p.Nome, formatarHoras(consecutivas), nova.DiaSemana, inicio, fim, formatarHoras(limite)),
+				Detalhes: map[string]any{"limite_horas": p.MaxHorasConsecutivas, "horas": minutosEmHoras(consecutivas), "horario_inicio": inicio, "horario_fim": fim},
+			})
+		}
+	}
+
+	if minimo := horasEmMinutos(p.DescansoMinimo); minimo > 0 {
+		anterior, seguinte := diasVizinhos(nova.DiaSemana)
+
+		// Da última aula do dia anterior até a nova aula
+		if ultima, ok := ultimaAula(aulas, anterior); ok {
+			descanso := minutosPorDia - ultima.HorarioFim.Minutos() + nova.HorarioInicio.Minutos()
+			if descanso < minimo {
+				violacoes = append(violacoes, violacaoDescanso(p, ultima, nova, descanso, minimo))
+			}
+		}
+
+		// Da nova aula até a primeira aula do dia seguinte
+		if primeira, ok := primeiraAula(aulas, seguinte); ok {
+			descanso := minutosPorDia - nova.HorarioFim.Minutos() + primeira.HorarioInicio.Minutos()
+			if descanso < minimo {
+				violacoes = append(violacoes, violacaoDescanso(p, nova, primeira, descanso, minimo))
+			}
+		}
+	}
+
+	return violacoes
+}
+
+// violacaoDescanso descreve um descanso insuficiente entre a última aula de um dia e a primeira do dia seguinte
+func violacaoDescanso(p models.Professor, ultima, primeira models.HorarioAula, descanso, minimo int) models.Violacao {
+	return models.Violacao{
+		Regra: "descanso_insuficiente",
+		Mensagem: fmt.Sprintf("o professor %s teria %s de descanso entre a aula de %s que termina às %s e a de %s às %s, abaixo do mínimo de %s",
+			p.Nome, formatarHoras(descanso), ultima.DiaSemana, ultima.HorarioFim, primeira.DiaSemana, primeira.HorarioInicio, formatarHoras(minimo)),
+		Detalhes: map[string]any{"minimo_horas": p.DescansoMinimo, "horas": minutosEmHoras(descanso)},
+	}
+}
+
+// blocoConsecutivo retorna o início e o fim do bloco de aulas seguidas do dia que contém a nova aula.
+// Aulas separadas por até intervaloMaximoConsecutivo minutos fazem parte do mesmo bloco.
+func blocoConsecutivo(aulas []models.HorarioAula, nova models.HorarioAula) (models.Horario, models.Horario) {
+	doDia := []models.HorarioAula{nova}
+	for _, a := range aulas {
+		if a.DiaSemana == nova.DiaSemana {
+			doDia = append(doDia, a)
+		}
+	}
+	sort.Slice(doDia, func(i, j int) bool { return doDia[i].HorarioInicio < doDia[j].HorarioInicio })
+
+	inicio, fim := doDia[0].HorarioInicio, doDia[0].HorarioFim
+	for _, a := range doDia[1:] {
+		if a.HorarioInicio.Minutos()-fim.Minutos() > intervaloMaximoConsecutivo {
+			// O bloco anterior terminou; se já continha a nova aula, ele é o resultado
+			if nova.HorarioInicio >= inicio && nova.HorarioFim <= fim {
+				return inicio, fim
+			}
+			inicio, fim = a.HorarioInicio, a.HorarioFim
+			continue
+		}
+		fim = max(fim, a.HorarioFim)
+	}
+	return inicio, fim
+}
+
+// diasVizinhos retorna o dia anterior e o dia seguinte da semana, que é tratada como circular
+func diasVizinhos(dia models.DiaSemana) (models.DiaSemana, models.DiaSemana) {
+	i := dia.Numero() - 1
+	n := len(models.DiasSemana)
+	return models.DiasSemana[(i+n-1)%n], models.DiasSemana[(i+1)%n]
+}
+
+// ultimaAula retorna a aula que termina mais tarde no dia
+func ultimaAula(aulas []models.HorarioAula, dia models.DiaSemana) (models.HorarioAula, bool) {
+	var ultima models.HorarioAula
+	encontrada := false
+	for _, a := range aulas {
+		if a.DiaSemana == dia && (!encontrada || a.HorarioFim > ultima.HorarioFim) {
+			ultima, encontrada = a, true
+		}
+	}
+	return ultima, encontrada
+}
+
+// primeiraAula retorna a aula que começa mais cedo no dia
+func primeiraAula(aulas []models.HorarioAula, dia models.DiaSemana) (models.HorarioAula, bool) {
+	var primeira models.HorarioAula
+	encontrada := false
+	for _, a := range aulas {
+		if a.DiaSemana == dia && (!encontrada || a.HorarioInicio < primeira.HorarioInicio) {
+			primeira, encontrada = a, true
+		}
+	}
+	return primeira, encontrada
+}
+
+// horasEmMinutos converte uma quantidade de horas em minutos
+func horasEmMinutos(horas float64) int {
+	return int(math.Round(horas * 60))
+}
+
+// minutosEmHoras converte uma quantidade de minutos em horas, com duas casas decimais
+func minutosEmHoras(minutos int) float64 {
+	return math.Round(float64(minutos)/60*100) / 100
+}
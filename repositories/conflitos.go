@@ -0,0 +1,295 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// codigoViolacaoExclusao é o código de erro do PostgreSQL para violação de restrição de exclusão
+const codigoViolacaoExclusao = "23P01"
+
+// namespaceBloqueioAlocacao é a primeira chave de bloqueio consultivo usada pelas alocações;
+// cada tipo de recurso usa a chave seguinte, na ordem de recursosVerificados
+const namespaceBloqueioAlocacao = 7000
+
+// ErrAlocacaoInvalida indica que o dia ou o intervalo de horário informado para a alocação é inválido
+var ErrAlocacaoInvalida = errors.New("dados da alocação inválidos")
+
+// validarAlocacao verifica se a alocação tem dia da semana e um intervalo de horário válido
+func validarAlocacao(a models.Alocacao) error {
+	if a.DiaSemana.Numero() == 0 {
+		return fmt.Errorf("%w: dia_semana é obrigatório", ErrAlocacaoInvalida)
+	}
+	if err := models.ValidarHorarioAula(a.HorarioInicio, a.HorarioFim); err != nil {
+		return fmt.Errorf("%w: %v", ErrAlocacaoInvalida, err)
+	}
+	return nil
+}
+
+// ConflitoError indica que uma alocação colide com alocações já existentes no mesmo dia e horário
+type ConflitoError struct {
+	Conflitos []models.Conflito
+}
+
+func (e *ConflitoError) Error() string {
+	mensagens := make([]string, 0, len(e.Conflitos))
+	for _, c := range e.Conflitos {
+		if c.AlocacaoID == 0 {
+			mensagens = append(mensagens, mensagemConflito[c.Recurso])
+			continue
+		}
+		quando := string(c.DiaSemana)
+		if c.Data != nil {
+			quando = fmt.Sprintf("%s (%s)", c.Data, c.DiaSemana)
+		}
+		origem := fmt.Sprintf("alocação %d", c.AlocacaoID)
+		if c.ExcecaoID != nil {
+			origem = fmt.Sprintf("exceção %d da alocação %d", *c.ExcecaoID, c.AlocacaoID)
+		}
+		mensagens = append(mensagens, fmt.Sprintf("%s (%s, %s %s-%s)",
+			mensagemConflito[c.Recurso], origem, quando, c.HorarioInicio, c.HorarioFim))
+	}
+	return strings.Join(mensagens, "; ")
+}
+
+// mensagemConflito descreve o conflito de cada tipo de recurso
+var mensagemConflito = map[string]string{
+	"sala":      "sala já está alocada neste horário",
+	"professor": "professor já está alocado neste horário",
+	"turma":     "turma já está alocada neste horário",
+}
+
+// recursosVerificados associa cada recurso verificado contra sobreposição à sua coluna em alocacoes
+// e à restrição de exclusão que garante a mesma regra no banco
+var recursosVerificados = []struct {
+	nome      string
+	coluna    string
+	restricao string
+	id        func(a models.Alocacao) int
+}{
+	{"sala", "sala_id", "alocacoes_sala_sem_sobreposicao", func(a models.Alocacao) int { return a.SalaID }},
+	{"professor", "professor_id", "alocacoes_professor_sem_sobreposicao", func(a models.Alocacao) int { return a.ProfessorID }},
+	{"turma", "turma_id", "alocacoes_turma_sem_sobreposicao", func(a models.Alocacao) int { return a.TurmaID }},
+}
+
+// verificarConflitos procura alocações do mesmo período letivo, exceto a própria, que ocupem a mesma
+// sala, o mesmo professor ou a mesma turma em horário sobreposto em alguma data em comum e retorna
+// um *ConflitoError com cada uma delas. Alocações com regra de recorrência só colidem se tiverem
+// aula na mesma data, como duas aulas quinzenais em semanas alternadas, que não colidem. As trocas
+// de sala e reposições de outras alocações que caem em datas da alocação também são verificadas.
+func verificarConflitos(db executor, a models.Alocacao) error {
+	periodo, err := getPeriodoLetivoByID(db, a.PeriodoLetivoID)
+	if err != nil {
+		return err
+	}
+
+	var conflitos []models.Conflito
+
+	for _, recurso := range recursosVerificados {
+		query := fmt.Sprintf(`
+			SELECT id, dia_semana, horario_inicio, horario_fim, intervalo_semanas, data_inicio, data_fim, semanas FROM alocacoes 
+			WHERE %s = $1 AND dia_semana = $2 AND 
+			horario_inicio < $4 AND horario_fim > $3 AND 
+			id != $5 AND periodo_letivo_id = $6
+			ORDER BY horario_inicio
+		`, recurso.coluna)
+
+		rows, err := db.Query(query, recurso.id(a), a.DiaSemana, a.HorarioInicio, a.HorarioFim, a.ID, a.PeriodoLetivoID)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			c := models.Conflito{Recurso: recurso.nome, RecursoID: recurso.id(a)}
+			var intervalo int
+			var inicio, fim *models.Data
+			var semanas pq.Int64Array
+			if err := rows.Scan(&c.AlocacaoID, &c.DiaSemana, &c.HorarioInicio, &c.HorarioFim, &intervalo, &inicio, &fim, &semanas); err != nil {
+				rows.Close()
+				return err
+			}
+
+			outra := models.Alocacao{DiaSemana: c.DiaSemana, Recorrencia: recorrenciaDasColunas(intervalo, inicio, fim, semanas)}
+			if acontecemJuntas(a, outra, periodo) {
+				conflitos = append(conflitos, c)
+			}
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	naData, err := conflitosComExcecoes(db, a, periodo, conflitos)
+	if err != nil {
+		return err
+	}
+	conflitos = append(conflitos, naData...)
+
+	if len(conflitos) > 0 {
+		return &ConflitoError{Conflitos: conflitos}
+	}
+
+	return nil
+}
+
+// conflitosComExcecoes procura, na agenda das datas em que a alocação tem aula, as trocas de sala e
+// reposições de outras alocações que ocupem a sala, o professor ou a turma dela em horário
+// sobreposto. Uma alocação já relatada em conflito pelo mesmo recurso não é repetida.
+func conflitosComExcecoes(db executor, a models.Alocacao, periodo models.PeriodoLetivo, relatados []models.Conflito) ([]models.Conflito, error) {
+	aulas, err := excecoesNoHorario(db, a, periodo)
+	if err != nil {
+		return nil, err
+	}
+
+	relatado := make(map[string]bool, len(relatados))
+	for _, c := range relatados {
+		relatado[fmt.Sprintf("%s/%d", c.Recurso, c.AlocacaoID)] = true
+	}
+
+	var conflitos []models.Conflito
+	for _, outra := range aulas {
+		ocupada := models.Alocacao{SalaID: outra.SalaID, ProfessorID: outra.ProfessorID, TurmaID: outra.TurmaID}
+		for _, recurso := range recursosVerificados {
+			chave := fmt.Sprintf("%s/%d", recurso.nome, outra.AlocacaoID)
+			if recurso.id(ocupada) != recurso.id(a) || relatado[chave] {
+				continue
+			}
+			relatado[chave] = true
+
+			data, inicio, fim := outra.Data, outra.HorarioInicio, outra.HorarioFim
+			conflitos = append(conflitos, models.Conflito{
+				Recurso:       recurso.nome,
+				RecursoID:     recurso.id(a),
+				AlocacaoID:    outra.AlocacaoID,
+				ExcecaoID:     outra.ExcecaoID,
+				Data:          &data,
+				DiaSemana:     outra.DiaSemana,
+				HorarioInicio: &inicio,
+				HorarioFim:    &fim,
+			})
+		}
+	}
+	return conflitos, nil
+}
+
+// excecoesNoHorario retorna as aulas de trocas de sala e reposições de outras alocações que caem
+// em datas da alocação com horário sobreposto ao dela, de acordo com a agenda
+func excecoesNoHorario(db executor, a models.Alocacao, periodo models.PeriodoLetivo) ([]models.AulaAgenda, error) {
+	datas := datasDaAlocacao(a, periodo)
+	if len(datas) == 0 {
+		return nil, nil
+	}
+
+	aulas, err := gerarAgenda(db, datas[0], datas[len(datas)-1], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	temAula := make(map[string]bool, len(datas))
+	for _, d := range datas {
+		temAula[d.String()] = true
+	}
+
+	var excecoes []models.AulaAgenda
+	for _, outra := range aulas {
+		if outra.ExcecaoID == nil || !outra.Acontece() || outra.AlocacaoID == a.ID || !temAula[outra.Data.String()] {
+			continue
+		}
+		if outra.HorarioInicio >= a.HorarioFim || a.HorarioInicio >= outra.HorarioFim {
+			continue
+		}
+		excecoes = append(excecoes, outra)
+	}
+	return excecoes, nil
+}
+
+// recursosOcupados retorna, para cada recurso de recursosVerificados, os IDs ocupados no horário da
+// alocação pelas mesmas regras de verificarConflitos: alocações do período letivo com aula em
+// alguma data em comum e trocas de sala e reposições nas datas da alocação. Um recurso fora da
+// lista pode receber a alocação sem conflito.
+func recursosOcupados(db executor, a models.Alocacao, periodo models.PeriodoLetivo) (map[string]map[int]bool, error) {
+	ocupados := make(map[string]map[int]bool, len(recursosVerificados))
+	for _, recurso := range recursosVerificados {
+		ocupados[recurso.nome] = make(map[int]bool)
+	}
+	ocupar := func(outra models.Alocacao) {
+		for _, recurso := range recursosVerificados {
+			ocupados[recurso.nome][recurso.id(outra)] = true
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT id, sala_id, professor_id, turma_id, dia_semana, intervalo_semanas, data_inicio, data_fim, semanas FROM alocacoes
+		WHERE periodo_letivo_id = $1 AND dia_semana = $2 AND horario_inicio < $4 AND horario_fim > $3 AND id != $5
+	`, periodo.ID, a.DiaSemana, a.HorarioInicio, a.HorarioFim, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var outra models.Alocacao
+		var intervalo int
+		var inicio, fim *models.Data
+		var semanas pq.Int64Array
+		if err := rows.Scan(&outra.ID, &outra.SalaID, &outra.ProfessorID, &outra.TurmaID, &outra.DiaSemana, &intervalo, &inicio, &fim, &semanas); err != nil {
+			return nil, err
+		}
+		outra.Recorrencia = recorrenciaDasColunas(intervalo, inicio, fim, semanas)
+		if acontecemJuntas(a, outra, periodo) {
+			ocupar(outra)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	excecoes, err := excecoesNoHorario(db, a, periodo)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range excecoes {
+		ocupar(models.Alocacao{SalaID: e.SalaID, ProfessorID: e.ProfessorID, TurmaID: e.TurmaID})
+	}
+
+	return ocupados, nil
+}
+
+// traduzirErroSobreposicao converte a violação de uma restrição de exclusão de alocacoes em um
+// *ConflitoError. Como a transação é abortada pela violação, a alocação concorrente não pode ser
+// consultada e apenas o recurso em conflito é informado.
+func traduzirErroSobreposicao(a models.Alocacao, err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != codigoViolacaoExclusao {
+		return err
+	}
+
+	for _, recurso := range recursosVerificados {
+		if recurso.restricao == pqErr.Constraint {
+			return &ConflitoError{Conflitos: []models.Conflito{{Recurso: recurso.nome, RecursoID: recurso.id(a)}}}
+		}
+	}
+
+	return err
+}
+
+// bloquearRecursos obtém bloqueios consultivos (advisory locks) da sala, do professor e da turma
+// da alocação até o fim da transação. Requisições simultâneas que envolvam algum desses recursos
+// esperam a transação terminar antes de verificar a disponibilidade, o que torna a verificação e
+// a gravação atômicas. Os bloqueios são sempre obtidos na mesma ordem para evitar deadlocks.
+func bloquearRecursos(db executor, a models.Alocacao) error {
+	for i, recurso := range recursosVerificados {
+		_, err := db.Exec("SELECT pg_advisory_xact_lock($1, $2)", namespaceBloqueioAlocacao+i, recurso.id(a))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
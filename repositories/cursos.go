@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrCursoInvalido indica que o curso ou a grade curricular informados são inválidos
+var ErrCursoInvalido = errors.New("dados do curso inválidos")
+
+// codigoViolacaoCheck é o código de erro do PostgreSQL para violação de restrição CHECK
+const codigoViolacaoCheck = "23514"
+
+// CursoRepository gerencia operações de banco de dados para cursos e suas grades curriculares
+type CursoRepository struct {
+	DB *sql.DB
+}
+
+// NewCursoRepository cria um novo repositório de cursos
+func NewCursoRepository(db *sql.DB) *CursoRepository {
+	return &CursoRepository{DB: db}
+}
+
+// GetAll retorna todos os cursos com as grades curriculares
+func (r *CursoRepository) GetAll() ([]models.Curso, error) {
+	rows, err := r.DB.Query("SELECT id, nome, semestres FROM cursos ORDER BY nome")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cursos := []models.Curso{}
+	indice := make(map[int]int)
+	for rows.Next() {
+		var c models.Curso
+		if err := rows.Scan(&c.ID, &c.Nome, &c.Semestres); err != nil {
+			return nil, err
+		}
+		c.GradeCurricular = []models.DisciplinaCurso{}
+		indice[c.ID] = len(cursos)
+		cursos = append(cursos, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	grades, err := gradesCurriculares(r.DB, "")
+	if err != nil {
+		return nil, err
+	}
+	for cursoID, grade := range grades {
+		if i, ok := indice[cursoID]; ok {
+			cursos[i].GradeCurricular = grade
+		}
+	}
+
+	return cursos, nil
+}
+
+// GetByID retorna um curso pelo ID com a grade curricular
+func (r *CursoRepository) GetByID(id int) (models.Curso, error) {
+	var c models.Curso
+	err := r.DB.QueryRow("SELECT id, nome, semestres FROM cursos WHERE id = $1", id).Scan(&c.ID, &c.Nome, &c.Semestres)
+	if err != nil {
+		return models.Curso{}, err
+	}
+
+	grades, err := gradesCurriculares(r.DB, " WHERE g.curso_id = $1", id)
+	if err != nil {
+		return models.Curso{}, err
+	}
+
+	c.GradeCurricular = grades[c.ID]
+	if c.GradeCurricular == nil {
+		c.GradeCurricular = []models.DisciplinaCurso{}
+	}
+	return c, nil
+}
+
+// Create cria um novo curso com a grade curricular
+func (r *CursoRepository) Create(c models.Curso) (models.Curso, error) {
+	if err := validarCurso(&c); err != nil {
+		return models.Curso{}, err
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return models.Curso{}, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("INSERT INTO cursos (nome, semestres) VALUES ($1, $2) RETURNING id", c.Nome, c.Semestres).Scan(&c.ID)
+	if err != nil {
+		return models.Curso{}, traduzirErroCurso(err)
+	}
+
+	if err := gravarGradeCurricular(tx, c); err != nil {
+		return models.Curso{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Curso{}, err
+	}
+	return r.GetByID(c.ID)
+}
+
+// Update atualiza um curso existente e substitui a grade curricular
+func (r *CursoRepository) Update(c models.Curso) error {
+	if err := validarCurso(&c); err != nil {
+		return err
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE cursos SET nome = $1, semestres = $2 WHERE id = $3", c.Nome, c.Semestres, c.ID)
+	if err != nil {
+		return traduzirErroCurso(err)
+	}
+	if err := exigirLinhaAfetada(result); err != nil {
+		return err
+	}
+
+	if err := gravarGradeCurricular(tx, c); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete remove um curso pelo ID; as turmas do curso ficam sem curso cadastrado
+func (r *CursoRepository) Delete(id int) error {
+	_, err := r.DB.Exec("DELETE FROM cursos WHERE id = $1", id)
+	return err
+}
+
+// gravarGradeCurricular substitui a grade curricular do curso
+func gravarGradeCurricular(db executor, c models.Curso) error {
+	_, err := db.Exec("DELETE FROM grade_curricular WHERE curso_id = $1", c.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range c.GradeCurricular {
+		_, err := db.Exec(`INSERT INTO grade_curricular (curso_id, disciplina_id, semestre, horas_semanais) VALUES ($1, $2, $3, $4)`,
+			c.ID, d.DisciplinaID, d.Semestre, d.HorasSemanais)
+		if err != nil {
+			return traduzirErroCurso(err)
+		}
+	}
+	return nil
+}
+
+// gradesCurriculares retorna as grades curriculares com o filtro informado, agrupadas por curso
+// e ordenadas por semestre e nome da disciplina
+func gradesCurriculares(db executor, filtro string, args ...any) (map[int][]models.DisciplinaCurso, error) {
+	rows, err := db.Query(`
+		SELECT g.curso_id, g.disciplina_id, d.nome, g.semestre, g.horas_semanais
+		FROM grade_curricular g JOIN disciplinas d ON d.id = g.disciplina_id`+filtro+`
+		ORDER BY g.curso_id, g.semestre, d.nome`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grades := make(map[int][]models.DisciplinaCurso)
+	for rows.Next() {
+		var cursoID int
+		var d models.DisciplinaCurso
+		if err := rows.Scan(&cursoID, &d.DisciplinaID, &d.Disciplina, &d.Semestre, &d.HorasSemanais); err != nil {
+			return nil, err
+		}
+		grades[cursoID] = append(grades[cursoID], d)
+	}
+
+	return grades, rows.Err()
+}
+
+// cargasCurriculares retorna a carga horária semanal de cada disciplina das turmas com o filtro
+// informado, segundo a grade curricular do curso da turma no semestre dela
+func cargasCurriculares(db executor, filtro string, args ...any) ([]models.CargaHorariaTurma, error) {
+	rows, err := db.Query(`
+		SELECT t.id, g.disciplina_id, g.horas_semanais
+		FROM turmas t JOIN grade_curricular g ON g.curso_id = t.curso_id AND g.semestre = t.semestre`+filtro+`
+		ORDER BY t.id, g.disciplina_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cargas := []models.CargaHorariaTurma{}
+	for rows.Next() {
+		var c models.CargaHorariaTurma
+		var disciplinaID int
+		if err := rows.Scan(&c.TurmaID, &disciplinaID, &c.HorasSemanais); err != nil {
+			return nil, err
+		}
+		c.DisciplinaID = &disciplinaID
+		cargas = append(cargas, c)
+	}
+
+	return cargas, rows.Err()
+}
+
+// validarCurso verifica o nome, a duração e a grade curricular do curso
+func validarCurso(c *models.Curso) error {
+	c.Nome = strings.TrimSpace(c.Nome)
+	if c.Nome == "" {
+		return fmt.Errorf("%w: nome é obrigatório", ErrCursoInvalido)
+	}
+	if c.Semestres < 0 {
+		return fmt.Errorf("%w: semestres não pode ser negativo", ErrCursoInvalido)
+	}
+
+	vistas := make(map[int]bool)
+	for _, d := range c.GradeCurricular {
+		if vistas[d.DisciplinaID] {
+			return fmt.Errorf("%w: a disciplina %d aparece mais de uma vez na grade curricular", ErrCursoInvalido, d.DisciplinaID)
+		}
+		vistas[d.DisciplinaID] = true
+
+		if d.Semestre < 1 || (c.Semestres > 0 && d.Semestre > c.Semestres) {
+			return fmt.Errorf("%w: semestre %d da disciplina %d fora do curso", ErrCursoInvalido, d.Semestre, d.DisciplinaID)
+		}
+		if d.HorasSemanais <= 0 {
+			return fmt.Errorf("%w: a carga horária da disciplina %d deve ser positiva", ErrCursoInvalido, d.DisciplinaID)
+		}
+	}
+	return nil
+}
+
+// traduzirErroCurso converte nomes de curso repetidos e referências a cursos ou disciplinas
+// inexistentes em ErrCursoInvalido
+func traduzirErroCurso(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch {
+	case pqErr.Code == codigoViolacaoUnicidade && pqErr.Constraint == "cursos_nome_unico":
+		return fmt.Errorf("%w: já existe um curso com este nome", ErrCursoInvalido)
+	case pqErr.Code == codigoViolacaoChaveEstrangeira && strings.Contains(pqErr.Constraint, "curso_id"):
+		return fmt.Errorf("%w: curso não encontrado", ErrCursoInvalido)
+	case pqErr.Code == codigoViolacaoChaveEstrangeira && strings.Contains(pqErr.Constraint, "disciplina_id"):
+		return fmt.Errorf("%w: disciplina não encontrada", ErrCursoInvalido)
+	}
+	return err
+}
+
+// traduzirErroTurma converte erros de disciplina, curso ou semestre de uma turma em erros de validação
+func traduzirErroTurma(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == codigoViolacaoCheck && strings.Contains(pqErr.Constraint, "semestre") {
+		return fmt.Errorf("%w: semestre não pode ser negativo", ErrCursoInvalido)
+	}
+	if errors.As(err, &pqErr) && strings.Contains(pqErr.Constraint, "curso_id") {
+		return traduzirErroCurso(err)
+	}
+	return traduzirErroDisciplina(err)
+}
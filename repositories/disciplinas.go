@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrDisciplinaInvalida indica que a disciplina informada é inválida
+var ErrDisciplinaInvalida = errors.New("dados da disciplina inválidos")
+
+// Códigos de erro do PostgreSQL usados para traduzir erros de disciplinas
+const (
+	codigoViolacaoUnicidade        = "23505"
+	codigoViolacaoChaveEstrangeira = "23503"
+)
+
+// DisciplinaRepository gerencia operações de banco de dados para disciplinas
+type DisciplinaRepository struct {
+	DB *sql.DB
+}
+
+// NewDisciplinaRepository cria um novo repositório de disciplinas
+func NewDisciplinaRepository(db *sql.DB) *DisciplinaRepository {
+	return &DisciplinaRepository{DB: db}
+}
+
+// disciplinaSelectQuery é a consulta base de disciplinas
+const disciplinaSelectQuery = `SELECT d.id, d.nome, d.codigo,
+	d.exige_computadores, d.exige_projetor, d.exige_bancadas, d.exige_acessibilidade FROM disciplinas d`
+
+// scanDisciplina lê uma linha da consulta base de disciplinas
+func scanDisciplina(row interface{ Scan(dest ...any) error }) (models.Disciplina, error) {
+	var d models.Disciplina
+	err := row.Scan(&d.ID, &d.Nome, &d.Codigo, &d.EquipamentosExigidos.Computadores, &d.EquipamentosExigidos.Projetor,
+		&d.EquipamentosExigidos.Bancadas, pq.Array(&d.EquipamentosExigidos.Acessibilidade))
+	if err != nil {
+		return models.Disciplina{}, err
+	}
+	return d, nil
+}
+
+// queryDisciplinas executa a consulta base de disciplinas com o filtro informado
+func queryDisciplinas(db executor, filtro string, args ...any) ([]models.Disciplina, error) {
+	rows, err := db.Query(disciplinaSelectQuery+filtro+" ORDER BY d.nome", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disciplinas := []models.Disciplina{}
+	for rows.Next() {
+		d, err := scanDisciplina(rows)
+		if err != nil {
+			return nil, err
+		}
+		disciplinas = append(disciplinas, d)
+	}
+
+	return disciplinas, rows.Err()
+}
+
+// GetAll retorna todas as disciplinas
+func (r *DisciplinaRepository) GetAll() ([]models.Disciplina, error) {
+	return queryDisciplinas(r.DB, "")
+}
+
+// GetByID retorna uma disciplina pelo ID
+func (r *DisciplinaRepository) GetByID(id int) (models.Disciplina, error) {
+	return getDisciplinaByID(r.DB, id)
+}
+
+// getDisciplinaByID retorna uma disciplina pelo ID usando a conexão ou transação informada
+func getDisciplinaByID(db executor, id int) (models.Disciplina, error) {
+	return scanDisciplina(db.QueryRow(disciplinaSelectQuery+" WHERE d.id = $1", id))
+}
+
+// Create cria uma nova disciplina
+func (r *DisciplinaRepository) Create(d models.Disciplina) (models.Disciplina, error) {
+	if err := validarDisciplina(&d); err != nil {
+		return models.Disciplina{}, err
+	}
+
+	query := `INSERT INTO disciplinas (nome, codigo, exige_computadores, exige_projetor, exige_bancadas, exige_acessibilidade)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6::TEXT[], '{}')) RETURNING id`
+
+	e := d.EquipamentosExigidos
+	err := r.DB.QueryRow(query, d.Nome, d.Codigo, e.Computadores, e.Projetor, e.Bancadas, pq.Array(e.Acessibilidade)).Scan(&d.ID)
+	if err != nil {
+		return models.Disciplina{}, traduzirErroDisciplina(err)
+	}
+
+	return d, nil
+}
+
+// Update atualiza uma disciplina existente
+func (r *DisciplinaRepository) Update(d models.Disciplina) error {
+	if err := validarDisciplina(&d); err != nil {
+		return err
+	}
+
+	query := `UPDATE disciplinas SET nome = $1, codigo = $2, exige_computadores = $3, exige_projetor = $4, exige_bancadas = $5,
+		exige_acessibilidade = COALESCE($6::TEXT[], '{}') WHERE id = $7`
+
+	e := d.EquipamentosExigidos
+	_, err := r.DB.Exec(query, d.Nome, d.Codigo, e.Computadores, e.Projetor, e.Bancadas, pq.Array(e.Acessibilidade), d.ID)
+	return traduzirErroDisciplina(err)
+}
+
+// Delete remove uma disciplina pelo ID. Professores deixam de lecioná-la, e turmas e alocações
+// ficam sem disciplina cadastrada.
+func (r *DisciplinaRepository) Delete(id int) error {
+	_, err := r.DB.Exec("DELETE FROM disciplinas WHERE id = $1", id)
+	return err
+}
+
+// GetByProfessor retorna as disciplinas que um professor leciona
+func (r *DisciplinaRepository) GetByProfessor(professorID int) ([]models.Disciplina, error) {
+	return queryDisciplinas(r.DB, " JOIN professor_disciplinas pd ON pd.disciplina_id = d.id WHERE pd.professor_id = $1", professorID)
+}
+
+// GetProfessores retorna os professores que lecionam uma disciplina
+func (r *DisciplinaRepository) GetProfessores(disciplinaID int) ([]models.Professor, error) {
+	rows, err := r.DB.Query(professorSelectQuery+`
+		WHERE p.id IN (SELECT professor_id FROM professor_disciplinas WHERE disciplina_id = $1)
+		ORDER BY p.nome`, disciplinaID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	professores := []models.Professor{}
+	for rows.Next() {
+		p, err := scanProfessor(rows)
+		if err != nil {
+			return nil, err
+		}
+		professores = append(professores, p)
+	}
+
+	return professores, rows.Err()
+}
+
+// VincularProfessor associa uma disciplina a um professor; associar de novo não tem efeito
+func (r *DisciplinaRepository) VincularProfessor(professorID, disciplinaID int) error {
+	_, err := r.DB.Exec(`INSERT INTO professor_disciplinas (professor_id, disciplina_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, professorID, disciplinaID)
+	return traduzirErroDisciplina(err)
+}
+
+// DesvincularProfessor remove a associação entre um professor e uma disciplina
+func (r *DisciplinaRepository) DesvincularProfessor(professorID, disciplinaID int) error {
+	result, err := r.DB.Exec("DELETE FROM professor_disciplinas WHERE professor_id = $1 AND disciplina_id = $2", professorID, disciplinaID)
+	if err != nil {
+		return err
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// gravarDisciplinasProfessor substitui as disciplinas que o professor leciona
+func gravarDisciplinasProfessor(db executor, professorID int, disciplinaIDs []int) error {
+	_, err := db.Exec("DELETE FROM professor_disciplinas WHERE professor_id = $1", professorID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`INSERT INTO professor_disciplinas (professor_id, disciplina_id)
+		SELECT DISTINCT $1::INT, UNNEST($2::INT[])`, professorID, arrayDeIDs(disciplinaIDs))
+	return traduzirErroDisciplina(err)
+}
+
+// validarDisciplina verifica se a disciplina tem nome e equipamentos exigidos válidos e remove
+// espaços nas pontas dos campos
+func validarDisciplina(d *models.Disciplina) error {
+	d.Nome = strings.TrimSpace(d.Nome)
+	d.Codigo = strings.TrimSpace(d.Codigo)
+	if d.Nome == "" {
+		return fmt.Errorf("%w: nome é obrigatório", ErrDisciplinaInvalida)
+	}
+	return validarEquipamentos(&d.EquipamentosExigidos)
+}
+
+// traduzirErroDisciplina converte nomes de disciplina repetidos e referências a disciplinas
+// inexistentes em ErrDisciplinaInvalida
+func traduzirErroDisciplina(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch {
+	case pqErr.Code == codigoViolacaoUnicidade && pqErr.Constraint == "disciplinas_nome_unico":
+		return fmt.Errorf("%w: já existe uma disciplina com este nome", ErrDisciplinaInvalida)
+	case pqErr.Code == codigoViolacaoChaveEstrangeira && strings.Contains(pqErr.Constraint, "disciplina_id"):
+		return fmt.Errorf("%w: disciplina não encontrada", ErrDisciplinaInvalida)
+	}
+	return err
+}
+
+// idsDoArray converte um array de inteiros lido do banco em uma lista de IDs
+func idsDoArray(valores pq.Int64Array) []int {
+	ids := make([]int, len(valores))
+	for i, v := range valores {
+		ids[i] = int(v)
+	}
+	return ids
+}
+
+// arrayDeIDs converte uma lista de IDs em um array de inteiros para o banco
+func arrayDeIDs(ids []int) pq.Int64Array {
+	valores := make(pq.Int64Array, len(ids))
+	for i, id := range ids {
+		valores[i] = int64(id)
+	}
+	return valores
+}
+
+// professorLecionaDisciplina verifica se a disciplina está entre as que o professor leciona
+func professorLecionaDisciplina(p models.Professor, disciplinaID int) bool {
+	return slices.Contains(p.DisciplinaIDs, disciplinaID)
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrDisponibilidadeInvalida indica que a janela de disponibilidade informada é inválida
+var ErrDisponibilidadeInvalida = errors.New("dados da disponibilidade inválidos")
+
+// DisponibilidadeRepository gerencia operações de banco de dados para a disponibilidade dos professores
+type DisponibilidadeRepository struct {
+	DB *sql.DB
+}
+
+// NewDisponibilidadeRepository cria um novo repositório de disponibilidade dos professores
+func NewDisponibilidadeRepository(db *sql.DB) *DisponibilidadeRepository {
+	return &DisponibilidadeRepository{DB: db}
+}
+
+// disponibilidadeSelectQuery é a consulta base das janelas de disponibilidade
+const disponibilidadeSelectQuery = `
+	SELECT id, professor_id, dia_semana, horario_inicio, horario_fim, tipo, motivo
+	FROM disponibilidades_professor
+`
+
+// scanDisponibilidade lê uma linha da consulta base de disponibilidade
+func scanDisponibilidade(row interface{ Scan(dest ...any) error }) (models.DisponibilidadeProfessor, error) {
+	var d models.DisponibilidadeProfessor
+	err := row.Scan(&d.ID, &d.ProfessorID, &d.DiaSemana, &d.HorarioInicio, &d.HorarioFim, &d.Tipo, &d.Motivo)
+	if err != nil {
+		return models.DisponibilidadeProfessor{}, err
+	}
+	return d, nil
+}
+
+// queryDisponibilidades executa a consulta base de disponibilidade com o filtro informado
+func queryDisponibilidades(db executor, filtro string, args ...any) ([]models.DisponibilidadeProfessor, error) {
+	rows, err := db.Query(disponibilidadeSelectQuery+filtro+" ORDER BY professor_id, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disponibilidades := []models.DisponibilidadeProfessor{}
+	for rows.Next() {
+		d, err := scanDisponibilidade(rows)
+		if err != nil {
+			return nil, err
+		}
+		disponibilidades = append(disponibilidades, d)
+	}
+
+	return disponibilidades, rows.Err()
+}
+
+// GetByProfessor retorna as janelas de disponibilidade de um professor
+func (r *DisponibilidadeRepository) GetByProfessor(professorID int) ([]models.DisponibilidadeProfessor, error) {
+	return queryDisponibilidades(r.DB, " WHERE professor_id = $1", professorID)
+}
+
+// GetByID retorna uma janela de disponibilidade de um professor
+func (r *DisponibilidadeRepository) GetByID(professorID, id int) (models.DisponibilidadeProfessor, error) {
+	return scanDisponibilidade(r.DB.QueryRow(disponibilidadeSelectQuery+" WHERE id = $1 AND professor_id = $2", id, professorID))
+}
+
+// Create cria uma nova janela de disponibilidade
+func (r *DisponibilidadeRepository) Create(d models.DisponibilidadeProfessor) (models.DisponibilidadeProfessor, error) {
+	if err := validarDisponibilidade(d); err != nil {
+		return models.DisponibilidadeProfessor{}, err
+	}
+
+	query := `INSERT INTO disponibilidades_professor (professor_id, dia_semana, horario_inicio, horario_fim, tipo, motivo)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	err := r.DB.QueryRow(query, d.ProfessorID, d.DiaSemana, d.HorarioInicio, d.HorarioFim, d.Tipo, d.Motivo).Scan(&d.ID)
+	if err != nil {
+		return models.DisponibilidadeProfessor{}, err
+	}
+
+	return d, nil
+}
+
+// Update atualiza uma janela de disponibilidade existente
+func (r *DisponibilidadeRepository) Update(d models.DisponibilidadeProfessor) error {
+	if err := validarDisponibilidade(d); err != nil {
+		return err
+	}
+
+	query := `UPDATE disponibilidades_professor SET dia_semana = $1, horario_inicio = $2, horario_fim = $3,
+			tipo = $4, motivo = $5 WHERE id = $6 AND professor_id = $7`
+
+	result, err := r.DB.Exec(query, d.DiaSemana, d.HorarioInicio, d.HorarioFim, d.Tipo, d.Motivo, d.ID, d.ProfessorID)
+	if err != nil {
+		return err
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// Delete remove uma janela de disponibilidade de um professor
+func (r *DisponibilidadeRepository) Delete(professorID, id int) error {
+	result, err := r.DB.Exec("DELETE FROM disponibilidades_professor WHERE id = $1 AND professor_id = $2", id, professorID)
+	if err != nil {
+		return err
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// exigirLinhaAfetada retorna sql.ErrNoRows quando o comando não alterou nenhuma linha
+func exigirLinhaAfetada(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// validarDisponibilidade verifica o dia, o intervalo e o tipo de uma janela de disponibilidade
+func validarDisponibilidade(d models.DisponibilidadeProfessor) error {
+	if d.DiaSemana.Numero() == 0 {
+		return fmt.Errorf("%w: dia_semana é obrigatório", ErrDisponibilidadeInvalida)
+	}
+	if err := models.ValidarIntervalo(d.HorarioInicio, d.HorarioFim); err != nil {
+		return fmt.Errorf("%w: %v", ErrDisponibilidadeInvalida, err)
+	}
+	if d.Tipo != models.Disponivel && d.Tipo != models.Indisponivel {
+		return fmt.Errorf("%w: tipo deve ser %q ou %q", ErrDisponibilidadeInvalida, models.Disponivel, models.Indisponivel)
+	}
+	return nil
+}
+
+// disponibilidadesPorProfessor retorna as janelas de disponibilidade de todos os professores, agrupadas por professor
+func disponibilidadesPorProfessor(db executor) (map[int][]models.DisponibilidadeProfessor, error) {
+	disponibilidades, err := queryDisponibilidades(db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	porProfessor := make(map[int][]models.DisponibilidadeProfessor)
+	for _, d := range disponibilidades {
+		porProfessor[d.ProfessorID] = append(porProfessor[d.ProfessorID], d)
+	}
+	return porProfessor, nil
+}
+
+// motivoIndisponibilidade verifica o intervalo contra as janelas de um professor e retorna por que ele
+// não pode lecionar nesse horário, ou uma string vazia se puder. O professor não pode lecionar em
+// horários indisponíveis e, se tiver janelas disponíveis, o intervalo deve estar coberto por elas.
+func motivoIndisponibilidade(janelas []models.DisponibilidadeProfessor, dia models.DiaSemana, inicio, fim models.Horario) string {
+	var disponiveis []models.DisponibilidadeProfessor
+	temDisponiveis := false
+	for _, j := range janelas {
+		if j.Tipo == models.Disponivel {
+			temDisponiveis = true
+		}
+		if j.DiaSemana != dia {
+			continue
+		}
+
+		switch j.Tipo {
+		case models.Indisponivel:
+			if j.HorarioInicio < fim && inicio < j.HorarioFim {
+				motivo := fmt.Sprintf("professor indisponível %s %s-%s", j.DiaSemana, j.HorarioInicio, j.HorarioFim)
+				if j.Motivo != "" {
+					motivo += " (" + j.Motivo + ")"
+				}
+				return motivo
+			}
+		case models.Disponivel:
+			disponiveis = append(disponiveis, j)
+		}
+	}
+
+	if !temDisponiveis {
+		return ""
+	}
+
+	// Percorrer as janelas disponíveis do dia em ordem, juntando as contíguas, até cobrir o intervalo
+	sort.Slice(disponiveis, func(i, k int) bool { return disponiveis[i].HorarioInicio < disponiveis[k].HorarioInicio })
+	coberto := inicio
+	for _, j := range disponiveis {
+		if j.HorarioInicio <= coberto && j.HorarioFim > coberto {
+			coberto = j.HorarioFim
+		}
+	}
+	if coberto >= fim {
+		return ""
+	}
+
+	return fmt.Sprintf("horário fora das janelas de disponibilidade do professor em %s", dia)
+}
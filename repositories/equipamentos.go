@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrEquipamentosInvalidos indica que o inventário ou os equipamentos exigidos informados são inválidos
+var ErrEquipamentosInvalidos = errors.New("equipamentos inválidos")
+
+// validarEquipamentos recusa quantidades negativas e remove recursos de acessibilidade vazios ou repetidos
+func validarEquipamentos(e *models.Equipamentos) error {
+	if e.Computadores < 0 || e.Bancadas < 0 {
+		return fmt.Errorf("%w: a quantidade de computadores e de bancadas não pode ser negativa", ErrEquipamentosInvalidos)
+	}
+	e.Acessibilidade = unirAcessibilidade(nil, e.Acessibilidade)
+	return nil
+}
+
+// somarEquipamentos combina duas listas de equipamentos exigidos, como os da turma e os da disciplina
+func somarEquipamentos(a, b models.Equipamentos) models.Equipamentos {
+	return models.Equipamentos{
+		Computadores:   max(a.Computadores, b.Computadores),
+		Projetor:       a.Projetor || b.Projetor,
+		Bancadas:       max(a.Bancadas, b.Bancadas),
+		Acessibilidade: unirAcessibilidade(a.Acessibilidade, b.Acessibilidade),
+	}
+}
+
+// unirAcessibilidade junta os recursos de acessibilidade das listas, sem vazios nem repetições
+func unirAcessibilidade(listas ...[]string) []string {
+	recursos := []string{}
+	vistos := make(map[string]bool)
+	for _, lista := range listas {
+		for _, recurso := range lista {
+			chave := normalizarTexto(recurso)
+			if chave == "" || vistos[chave] {
+				continue
+			}
+			vistos[chave] = true
+			recursos = append(recursos, strings.TrimSpace(recurso))
+		}
+	}
+	return recursos
+}
+
+// equipamentosFaltantes lista os equipamentos exigidos que o inventário da sala não atende
+func equipamentosFaltantes(inventario, exigidos models.Equipamentos) []string {
+	var faltantes []string
+	if exigidos.Computadores > inventario.Computadores {
+		faltantes = append(faltantes, fmt.Sprintf("%d computadores (a sala tem %d)", exigidos.Computadores, inventario.Computadores))
+	}
+	if exigidos.Projetor && !inventario.Projetor {
+		faltantes = append(faltantes, "projetor")
+	}
+	if exigidos.Bancadas > inventario.Bancadas {
+		faltantes = append(faltantes, fmt.Sprintf("%d bancadas (a sala tem %d)", exigidos.Bancadas, inventario.Bancadas))
+	}
+
+	disponiveis := make(map[string]bool)
+	for _, recurso := range inventario.Acessibilidade {
+		disponiveis[normalizarTexto(recurso)] = true
+	}
+	for _, recurso := range exigidos.Acessibilidade {
+		if !disponiveis[normalizarTexto(recurso)] {
+			faltantes = append(faltantes, strings.TrimSpace(recurso))
+		}
+	}
+
+	return faltantes
+}
+
+// descreverEquipamentos lista os equipamentos, como "30 computadores, projetor, rampa"
+func descreverEquipamentos(e models.Equipamentos) string {
+	var itens []string
+	if e.Computadores > 0 {
+		itens = append(itens, fmt.Sprintf("%d computadores", e.Computadores))
+	}
+	if e.Projetor {
+		itens = append(itens, "projetor")
+	}
+	if e.Bancadas > 0 {
+		itens = append(itens, fmt.Sprintf("%d bancadas", e.Bancadas))
+	}
+	itens = append(itens, e.Acessibilidade...)
+	return strings.Join(itens, ", ")
+}
+
+// equipamentosDaAula retorna os equipamentos exigidos em uma aula da turma: os da própria turma
+// somados aos da disciplina da aula, informada na alocação ou, sem ela, a da turma
+func equipamentosDaAula(db executor, t models.Turma, disciplinaID *int) (models.Equipamentos, error) {
+	if disciplinaID == nil {
+		disciplinaID = t.DisciplinaID
+	}
+	if disciplinaID == nil {
+		return t.EquipamentosExigidos, nil
+	}
+
+	d, err := getDisciplinaByID(db, *disciplinaID)
+	if err != nil {
+		return models.Equipamentos{}, err
+	}
+	return somarEquipamentos(t.EquipamentosExigidos, d.EquipamentosExigidos), nil
+}
+
+// aplicarEquipamentosDisciplinas soma aos equipamentos exigidos de cada turma os da disciplina dela,
+// para que as salas oferecidas pela alocação automática atendam as aulas da disciplina
+func aplicarEquipamentosDisciplinas(db executor, turmas []models.Turma) error {
+	disciplinas, err := queryDisciplinas(db, "")
+	if err != nil {
+		return err
+	}
+
+	porID := make(map[int]models.Disciplina, len(disciplinas))
+	for _, d := range disciplinas {
+		porID[d.ID] = d
+	}
+
+	for i, t := range turmas {
+		if t.DisciplinaID == nil {
+			continue
+		}
+		if d, ok := porID[*t.DisciplinaID]; ok {
+			turmas[i].EquipamentosExigidos = somarEquipamentos(t.EquipamentosExigidos, d.EquipamentosExigidos)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,483 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrExcecaoInvalida indica que a exceção informada para uma aula da alocação é inválida
+var ErrExcecaoInvalida = errors.New("dados da exceção inválidos")
+
+// ExcecaoAlocacaoRepository gerencia operações de banco de dados para as exceções das alocações em datas específicas
+type ExcecaoAlocacaoRepository struct {
+	DB *sql.DB
+}
+
+// NewExcecaoAlocacaoRepository cria um novo repositório de exceções de alocações
+func NewExcecaoAlocacaoRepository(db *sql.DB) *ExcecaoAlocacaoRepository {
+	return &ExcecaoAlocacaoRepository{DB: db}
+}
+
+// excecaoSelectQuery é a consulta base das exceções de alocações
+const excecaoSelectQuery = `
+	SELECT id, alocacao_id, data, tipo, sala_id, nova_data, horario_inicio, horario_fim, motivo
+	FROM excecoes_alocacao
+`
+
+// regrasExcecao são as regras de alocação verificadas para a aula resultante de uma troca de sala ou
+// reposição. A carga horária do professor e o deslocamento entre blocos dependem das outras aulas da
+// data e são verificados na agenda, em validarRegrasNaData.
+var regrasExcecao = []regraAlocacao{
+	verificarCapacidade,
+	verificarTipoSala,
+	verificarDisponibilidadeProfessor,
+	verificarTurnoTurma,
+	verificarEquipamentos,
+}
+
+// scanExcecao lê uma linha da consulta base de exceções
+func scanExcecao(row interface{ Scan(dest ...any) error }) (models.ExcecaoAlocacao, error) {
+	var e models.ExcecaoAlocacao
+	err := row.Scan(&e.ID, &e.AlocacaoID, &e.Data, &e.Tipo, &e.SalaID, &e.NovaData, &e.HorarioInicio, &e.HorarioFim, &e.Motivo)
+	return e, err
+}
+
+// queryExcecoes executa a consulta base de exceções com o filtro informado, em ordem de data
+func queryExcecoes(db executor, filtro string, args ...any) ([]models.ExcecaoAlocacao, error) {
+	rows, err := db.Query(excecaoSelectQuery+filtro+" ORDER BY data, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	excecoes := []models.ExcecaoAlocacao{}
+	for rows.Next() {
+		e, err := scanExcecao(rows)
+		if err != nil {
+			return nil, err
+		}
+		excecoes = append(excecoes, e)
+	}
+
+	return excecoes, rows.Err()
+}
+
+// GetByAlocacao retorna as exceções de uma alocação
+func (r *ExcecaoAlocacaoRepository) GetByAlocacao(alocacaoID int) ([]models.ExcecaoAlocacao, error) {
+	return queryExcecoes(r.DB, " WHERE alocacao_id = $1", alocacaoID)
+}
+
+// GetByID retorna uma exceção de uma alocação
+func (r *ExcecaoAlocacaoRepository) GetByID(alocacaoID, id int) (models.ExcecaoAlocacao, error) {
+	return scanExcecao(r.DB.QueryRow(excecaoSelectQuery+" WHERE id = $1 AND alocacao_id = $2", id, alocacaoID))
+}
+
+// Create registra uma exceção para uma aula da alocação. A verificação da aula resultante e a
+// gravação acontecem na mesma transação, com os recursos bloqueados, como na criação de alocações.
+func (r *ExcecaoAlocacaoRepository) Create(e models.ExcecaoAlocacao) (models.ExcecaoAlocacao, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return models.ExcecaoAlocacao{}, err
+	}
+	defer tx.Rollback()
+
+	if err := prepararExcecao(tx, &e); err != nil {
+		return models.ExcecaoAlocacao{}, err
+	}
+
+	query := `INSERT INTO excecoes_alocacao (alocacao_id, data, tipo, sala_id, nova_data, horario_inicio, horario_fim, motivo)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
+	err = tx.QueryRow(query, e.AlocacaoID, e.Data, e.Tipo, e.SalaID, e.NovaData, e.HorarioInicio, e.HorarioFim, e.Motivo).Scan(&e.ID)
+	if err != nil {
+		return models.ExcecaoAlocacao{}, traduzirErroExcecao(e, err)
+	}
+
+	return e, tx.Commit()
+}
+
+// Update atualiza uma exceção existente de uma alocação
+func (r *ExcecaoAlocacaoRepository) Update(e models.ExcecaoAlocacao) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := prepararExcecao(tx, &e); err != nil {
+		return err
+	}
+
+	query := `UPDATE excecoes_alocacao SET data = $1, tipo = $2, sala_id = $3, nova_data = $4,
+			horario_inicio = $5, horario_fim = $6, motivo = $7 WHERE id = $8 AND alocacao_id = $9`
+
+	result, err := tx.Exec(query, e.Data, e.Tipo, e.SalaID, e.NovaData, e.HorarioInicio, e.HorarioFim, e.Motivo, e.ID, e.AlocacaoID)
+	if err != nil {
+		return traduzirErroExcecao(e, err)
+	}
+	if err := exigirLinhaAfetada(result); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete remove uma exceção de uma alocação; a aula volta a seguir o padrão semanal
+func (r *ExcecaoAlocacaoRepository) Delete(alocacaoID, id int) error {
+	result, err := r.DB.Exec("DELETE FROM excecoes_alocacao WHERE id = $1 AND alocacao_id = $2", id, alocacaoID)
+	if err != nil {
+		return err
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// prepararExcecao valida a exceção contra a alocação e seu período letivo e, na troca de sala e na
+// reposição, verifica se a aula resultante atende às regras de alocação e não colide com outras
+// aulas na mesma data
+func prepararExcecao(db executor, e *models.ExcecaoAlocacao) error {
+	a, err := getAlocacaoByID(db, e.AlocacaoID)
+	if err != nil {
+		return err
+	}
+
+	periodo, err := getPeriodoLetivoByID(db, a.PeriodoLetivoID)
+	if err != nil {
+		return err
+	}
+
+	if err := validarExcecao(e, a, periodo); err != nil {
+		return err
+	}
+	if e.Tipo == models.ExcecaoCancelada {
+		return nil
+	}
+
+	aula, data := aulaDaExcecao(*e, a)
+	if e.Tipo == models.ExcecaoReposicao {
+		naoLetivos, err := diasNaoLetivosEntre(db, data, data)
+		if err != nil {
+			return err
+		}
+		if dia, ok := naoLetivos[data.String()]; ok {
+			return fmt.Errorf("%w: %s é um dia não letivo (%s)", ErrExcecaoInvalida, data, dia.Descricao)
+		}
+	}
+
+	if err := bloquearRecursos(db, aula); err != nil {
+		return err
+	}
+	if err := verificarConflitosNaData(db, *e, aula, data); err != nil {
+		return err
+	}
+	return validarRegrasNaData(db, *e, aula, data, periodo)
+}
+
+// validarExcecao verifica se a data da exceção é uma aula da alocação e se o tipo informado traz
+// apenas os campos que lhe cabem
+func validarExcecao(e *models.ExcecaoAlocacao, a models.Alocacao, periodo models.PeriodoLetivo) error {
+	if e.Data.IsZero() {
+		return fmt.Errorf("%w: data é obrigatória", ErrExcecaoInvalida)
+	}
+	if e.Data.DiaSemana() != a.DiaSemana {
+		return fmt.Errorf("%w: %s cai em %s e a alocação é de %s", ErrExcecaoInvalida, e.Data, e.Data.DiaSemana(), a.DiaSemana)
+	}
+	if !dataNoPeriodo(e.Data, periodo) {
+		return fmt.Errorf("%w: %s está fora do período letivo %s (%s a %s)", ErrExcecaoInvalida, e.Data, periodo.Nome, periodo.DataInicio, periodo.DataFim)
+	}
+	if !aulaNaData(a, periodo, e.Data) {
+		return fmt.Errorf("%w: a alocação não tem aula em %s pela sua recorrência", ErrExcecaoInvalida, e.Data)
+	}
+
+	e.Tipo = normalizarTipo(e.Tipo)
+	e.Motivo = strings.TrimSpace(e.Motivo)
+	temHorario := e.HorarioInicio != nil || e.HorarioFim != nil
+
+	switch e.Tipo {
+	case models.ExcecaoCancelada:
+		if e.SalaID != nil || e.NovaData != nil || temHorario {
+			return fmt.Errorf("%w: uma aula cancelada não aceita sala_id, nova_data nem horários", ErrExcecaoInvalida)
+		}
+	case models.ExcecaoTrocaSala:
+		if e.SalaID == nil {
+			return fmt.Errorf("%w: sala_id é obrigatório na troca de sala", ErrExcecaoInvalida)
+		}
+		if *e.SalaID == a.SalaID {
+			return fmt.Errorf("%w: a sala %d já é a sala da alocação", ErrExcecaoInvalida, a.SalaID)
+		}
+		if e.NovaData != nil || temHorario {
+			return fmt.Errorf("%w: a troca de sala não aceita nova_data nem horários; use uma reposição", ErrExcecaoInvalida)
+		}
+	case models.ExcecaoReposicao:
+		if e.NovaData == nil {
+			return fmt.Errorf("%w: nova_data é obrigatória na reposição", ErrExcecaoInvalida)
+		}
+		if !dataNoPeriodo(*e.NovaData, periodo) {
+			return fmt.Errorf("%w: nova_data (%s) está fora do período letivo %s (%s a %s)", ErrExcecaoInvalida, e.NovaData, periodo.Nome, periodo.DataInicio, periodo.DataFim)
+		}
+		if (e.HorarioInicio == nil) != (e.HorarioFim == nil) {
+			return fmt.Errorf("%w: horario_inicio e horario_fim devem ser informados juntos", ErrExcecaoInvalida)
+		}
+		if e.HorarioInicio != nil {
+			if err := models.ValidarIntervalo(*e.HorarioInicio, *e.HorarioFim); err != nil {
+				return fmt.Errorf("%w: %v", ErrExcecaoInvalida, err)
+			}
+		}
+		if e.NovaData.Equal(e.Data.Time) && (e.HorarioInicio == nil || *e.HorarioInicio == a.HorarioInicio) {
+			return fmt.Errorf("%w: a reposição deve mudar a data ou o horário da aula", ErrExcecaoInvalida)
+		}
+	default:
+		return fmt.Errorf("%w: tipo deve ser %q, %q ou %q", ErrExcecaoInvalida, models.ExcecaoCancelada, models.ExcecaoTrocaSala, models.ExcecaoReposicao)
+	}
+	return nil
+}
+
+// aulaNaData verifica se a alocação tem aula na data de acordo com a sua regra de recorrência
+func aulaNaData(a models.Alocacao, p models.PeriodoLetivo, data models.Data) bool {
+	return slices.ContainsFunc(datasDaAlocacao(a, p), func(d models.Data) bool { return d.Equal(data.Time) })
+}
+
+// descartarExcecoesForaDasAulas remove as exceções da alocação cujas datas deixaram de ser aulas
+// dela, como depois de mudar o dia da semana, o período letivo ou a recorrência
+func descartarExcecoesForaDasAulas(db executor, a models.Alocacao, p models.PeriodoLetivo) error {
+	excecoes, err := queryExcecoes(db, " WHERE alocacao_id = $1", a.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range excecoes {
+		if aulaNaData(a, p, e.Data) {
+			continue
+		}
+		if _, err := db.Exec("DELETE FROM excecoes_alocacao WHERE id = $1", e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataNoPeriodo verifica se a data está entre o início e o fim do período letivo
+func dataNoPeriodo(d models.Data, p models.PeriodoLetivo) bool {
+	return !d.Before(p.DataInicio.Time) && !d.After(p.DataFim.Time)
+}
+
+// aulaDaExcecao retorna a alocação com a sala, o dia e o horário da aula resultante da exceção, e a data dessa aula
+func aulaDaExcecao(e models.ExcecaoAlocacao, a models.Alocacao) (models.Alocacao, models.Data) {
+	aula, data := a, e.Data
+	if e.SalaID != nil {
+		aula.SalaID = *e.SalaID
+	}
+	if e.NovaData != nil {
+		data = *e.NovaData
+		aula.DiaSemana = data.DiaSemana()
+	}
+	if e.HorarioInicio != nil {
+		aula.HorarioInicio, aula.HorarioFim = *e.HorarioInicio, *e.HorarioFim
+	}
+	return aula, data
+}
+
+// verificarConflitosNaData procura aulas da data que ocupem a sala, o professor ou a turma da aula
+// resultante da exceção em horário sobreposto. A aula original da alocação na data da exceção não
+// conta, pois é ela que a exceção substitui.
+func verificarConflitosNaData(db executor, e models.ExcecaoAlocacao, aula models.Alocacao, data models.Data) error {
+	aulas, err := outrasAulas(db, e, data, data)
+	if err != nil {
+		return err
+	}
+
+	var conflitos []models.Conflito
+	for _, outra := range aulas {
+		if outra.HorarioInicio >= aula.HorarioFim || aula.HorarioInicio >= outra.HorarioFim {
+			continue
+		}
+
+		ocupada := models.Alocacao{SalaID: outra.SalaID, ProfessorID: outra.ProfessorID, TurmaID: outra.TurmaID}
+		for _, recurso := range recursosVerificados {
+			if recurso.id(ocupada) != recurso.id(aula) {
+				continue
+			}
+			inicio, fim := outra.HorarioInicio, outra.HorarioFim
+			conflitos = append(conflitos, models.Conflito{
+				Recurso:       recurso.nome,
+				RecursoID:     recurso.id(aula),
+				AlocacaoID:    outra.AlocacaoID,
+				ExcecaoID:     outra.ExcecaoID,
+				Data:          &data,
+				DiaSemana:     outra.DiaSemana,
+				HorarioInicio: &inicio,
+				HorarioFim:    &fim,
+			})
+		}
+	}
+
+	if len(conflitos) > 0 {
+		return &ConflitoError{Conflitos: conflitos}
+	}
+	return nil
+}
+
+// outrasAulas retorna as aulas que acontecem entre as datas de acordo com a agenda, sem a exceção
+// informada e sem a aula original que ela substitui
+func outrasAulas(db executor, e models.ExcecaoAlocacao, inicio, fim models.Data) ([]models.AulaAgenda, error) {
+	aulas, err := gerarAgenda(db, inicio, fim, e.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	outras := []models.AulaAgenda{}
+	for _, a := range aulas {
+		if !a.Acontece() || (a.AlocacaoID == e.AlocacaoID && a.ExcecaoID == nil && a.Data.Equal(e.Data.Time)) {
+			continue
+		}
+		outras = append(outras, a)
+	}
+	return outras, nil
+}
+
+// validarRegrasNaData verifica as regras de alocação da aula resultante da exceção, os bloqueios da
+// sala na data dessa aula, o deslocamento entre blocos desde as aulas vizinhas da data e, na
+// reposição, a carga horária do professor na semana da nova data, reunindo as violações em um único
+// ValidacaoError
+func validarRegrasNaData(db executor, e models.ExcecaoAlocacao, aula models.Alocacao, data models.Data, periodo models.PeriodoLetivo) error {
+	professor, err := getProfessorByID(db, aula.ProfessorID)
+	if err != nil {
+		return err
+	}
+
+	sala, err := getSalaByID(db, aula.SalaID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: sala %d não encontrada", ErrExcecaoInvalida, aula.SalaID)
+	}
+	if err != nil {
+		return err
+	}
+
+	turma, err := getTurmaByID(db, aula.TurmaID)
+	if err != nil {
+		return err
+	}
+
+	recursos := recursosRegra{professor: professor, sala: sala, turma: turma, periodo: periodo}
+
+	var violacoes []models.Violacao
+	for _, regra := range regrasExcecao {
+		v, err := regra(db, aula, recursos)
+		if err != nil {
+			return err
+		}
+		violacoes = append(violacoes, v...)
+	}
+
+	bloqueios, err := queryBloqueios(db, " WHERE sala_id = $1", aula.SalaID)
+	if err != nil {
+		return err
+	}
+	for _, b := range bloqueios {
+		if bloqueioAfeta(b, aula.DiaSemana, aula.HorarioInicio, aula.HorarioFim, data, data) {
+			violacoes = append(violacoes, models.Violacao{
+				Regra:    "sala_bloqueada",
+				Mensagem: fmt.Sprintf("a sala %s está bloqueada %s", sala.Numero, descreverBloqueio(b)),
+				Detalhes: map[string]any{
+					"bloqueio": b,
+				},
+			})
+			break
+		}
+	}
+
+	v, err := deslocamentoNaData(db, e, aula, data, sala)
+	if err != nil {
+		return err
+	}
+	violacoes = append(violacoes, v...)
+
+	if e.Tipo == models.ExcecaoReposicao {
+		v, err := cargaNaSemana(db, e, aula, data, professor)
+		if err != nil {
+			return err
+		}
+		violacoes = append(violacoes, v...)
+	}
+
+	if len(violacoes) > 0 {
+		return &ValidacaoError{Violacoes: violacoes}
+	}
+	return nil
+}
+
+// cargaNaSemana verifica os limites de carga horária do professor com a aula da exceção somada às
+// aulas que ele tem, de acordo com a agenda, na semana (de segunda a domingo) da data da aula
+func cargaNaSemana(db executor, e models.ExcecaoAlocacao, aula models.Alocacao, data models.Data, professor models.Professor) ([]models.Violacao, error) {
+	segunda := data.AdicionarDias(1 - data.DiaSemana().Numero())
+	aulas, err := outrasAulas(db, e, segunda, segunda.AdicionarDias(6))
+	if err != nil {
+		return nil, err
+	}
+
+	var semana []models.HorarioAula
+	for _, a := range aulas {
+		if a.ProfessorID == aula.ProfessorID {
+			semana = append(semana, models.HorarioAula{DiaSemana: a.DiaSemana, HorarioInicio: a.HorarioInicio, HorarioFim: a.HorarioFim})
+		}
+	}
+
+	nova := models.HorarioAula{DiaSemana: aula.DiaSemana, HorarioInicio: aula.HorarioInicio, HorarioFim: aula.HorarioFim}
+	return violacoesCargaProfessor(professor, semana, nova), nil
+}
+
+// deslocamentoNaData verifica o deslocamento entre o bloco da sala da aula da exceção e os das aulas
+// vizinhas do professor e da turma na mesma data, de acordo com a agenda
+func deslocamentoNaData(db executor, e models.ExcecaoAlocacao, aula models.Alocacao, data models.Data, sala models.Sala) ([]models.Violacao, error) {
+	if sala.BlocoID == nil {
+		return nil, nil
+	}
+
+	aulas, err := outrasAulas(db, e, data, data)
+	if err != nil {
+		return nil, err
+	}
+
+	blocos := map[int]*int{sala.ID: sala.BlocoID}
+	var candidatas []aulaVizinha
+	for _, a := range aulas {
+		if a.ProfessorID != aula.ProfessorID && a.TurmaID != aula.TurmaID {
+			continue
+		}
+
+		bloco, ok := blocos[a.SalaID]
+		if !ok {
+			s, err := getSalaByID(db, a.SalaID)
+			if err != nil {
+				return nil, err
+			}
+			bloco, blocos[a.SalaID] = s.BlocoID, s.BlocoID
+		}
+
+		candidatas = append(candidatas, aulaVizinha{
+			HorarioAula: models.HorarioAula{DiaSemana: a.DiaSemana, HorarioInicio: a.HorarioInicio, HorarioFim: a.HorarioFim},
+			AlocacaoID:  a.AlocacaoID,
+			BlocoID:     bloco,
+			professorID: a.ProfessorID,
+			turmaID:     a.TurmaID,
+		})
+	}
+
+	violacoes, _, err := compararDeslocamentos(db, aula, sala, escolherVizinhas(aula, candidatas))
+	return violacoes, err
+}
+
+// traduzirErroExcecao converte o cadastro de duas exceções para a mesma aula em ErrExcecaoInvalida
+func traduzirErroExcecao(e models.ExcecaoAlocacao, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == codigoViolacaoUnicidade && pqErr.Constraint == "excecoes_alocacao_data_unica" {
+		return fmt.Errorf("%w: já existe uma exceção para a aula de %s", ErrExcecaoInvalida, e.Data)
+	}
+	return err
+}
@@ -0,0 +1,370 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrGradeInvalida indica que as janelas de aula ou as cargas horárias informadas são inválidas
+var ErrGradeInvalida = errors.New("dados da grade semanal inválidos")
+
+// bonusContinuidadeProfessor favorece manter o mesmo professor em todas as aulas de uma turma na semana
+const bonusContinuidadeProfessor = 1000
+
+// chaveCarga identifica a carga horária de uma turma em uma disciplina. Disciplina zero indica
+// a carga da turma sem disciplina cadastrada.
+type chaveCarga struct {
+	turmaID      int
+	disciplinaID int
+}
+
+// chaveDaTurma retorna a chave da carga atendida por uma aula da turma
+func chaveDaTurma(t models.Turma) chaveCarga {
+	c := chaveCarga{turmaID: t.ID}
+	if t.DisciplinaID != nil {
+		c.disciplinaID = *t.DisciplinaID
+	}
+	return c
+}
+
+// janelaAula é uma janela de aula da semana já validada
+type janelaAula struct {
+	models.HorarioAula
+}
+
+// Duracao retorna a duração da janela em minutos
+func (j janelaAula) Duracao() int {
+	return j.HorarioFim.Minutos() - j.HorarioInicio.Minutos()
+}
+
+// SobrepoeA verifica se duas janelas acontecem no mesmo dia com horários sobrepostos
+func (j janelaAula) SobrepoeA(outra janelaAula) bool {
+	return j.DiaSemana == outra.DiaSemana && j.HorarioInicio < outra.HorarioFim && outra.HorarioInicio < j.HorarioFim
+}
+
+// SimularGradeSemanal calcula a grade semanal do período letivo informado (zero para o atual) sem
+// gravá-la e guarda o plano para ser aplicado pelo token
+func (r *AlocacaoRepository) SimularGradeSemanal(periodoLetivoID int, horarios []models.HorarioAula, cargas []models.CargaHorariaTurma) (models.PlanoAlocacao, error) {
+	plano, err := r.planejarGradeSemanal(periodoLetivoID, horarios, cargas)
+	if err != nil {
+		return models.PlanoAlocacao{}, err
+	}
+
+	return r.salvarPlano(plano)
+}
+
+// GerarGradeSemanal monta e grava uma grade semanal sem conflitos a partir das janelas de aula
+// disponíveis e da carga horária semanal de cada turma, no período letivo informado (zero para o atual)
+func (r *AlocacaoRepository) GerarGradeSemanal(periodoLetivoID int, horarios []models.HorarioAula, cargas []models.CargaHorariaTurma, transacional bool) (models.ResultadoAlocacaoAutomatica, error) {
+	plano, err := r.planejarGradeSemanal(periodoLetivoID, horarios, cargas)
+	if err != nil {
+		return models.ResultadoAlocacaoAutomatica{}, err
+	}
+
+	var resultado models.ResultadoAlocacaoAutomatica
+	if !transacional {
+		resultado = gravarLote(r.DB, plano.Alocacoes, false)
+	} else {
+		tx, err := r.DB.Begin()
+		if err != nil {
+			return models.ResultadoAlocacaoAutomatica{}, err
+		}
+		defer tx.Rollback()
+
+		resultado, err = finalizarLote(tx, gravarLote(tx, plano.Alocacoes, true))
+		if err != nil {
+			return models.ResultadoAlocacaoAutomatica{}, err
+		}
+	}
+
+	resultado.NaoAlocados = plano.NaoAlocados
+	return r.relatarSatisfacao(resultado)
+}
+
+// planejarGradeSemanal percorre as janelas de aula e, em cada uma, combina os recursos ainda livres
+// com as turmas que ainda precisam de horas, sem gravar nada. Os recursos já usados pela própria
+// grade em janelas sobrepostas são descartados, para que o resultado não tenha conflitos. Sem
+// cargas informadas, vale a carga de cada disciplina da grade curricular do curso das turmas.
+func (r *AlocacaoRepository) planejarGradeSemanal(periodoLetivoID int, horarios []models.HorarioAula, cargas []models.CargaHorariaTurma) (models.PlanoAlocacao, error) {
+	if len(horarios) == 0 {
+		return models.PlanoAlocacao{}, fmt.Errorf("%w: informe ao menos uma janela de aula", ErrGradeInvalida)
+	}
+
+	periodo, err := resolverPeriodoLetivo(r.DB, periodoLetivoID)
+	if err != nil {
+		return models.PlanoAlocacao{}, err
+	}
+	if len(cargas) == 0 {
+		var err error
+		cargas, err = cargasCurriculares(r.DB, "")
+		if err != nil {
+			return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter a carga horária das grades curriculares: %v", err)
+		}
+	}
+	if len(cargas) == 0 {
+		return models.PlanoAlocacao{}, fmt.Errorf("%w: informe a carga horária de ao menos uma turma ou associe as turmas a um semestre de curso com grade curricular", ErrGradeInvalida)
+	}
+
+	janelas := make([]janelaAula, 0, len(horarios))
+	for _, h := range horarios {
+		j, err := novaJanelaAula(h)
+		if err != nil {
+			return models.PlanoAlocacao{}, err
+		}
+		janelas = append(janelas, j)
+	}
+
+	// Carga restante, em minutos, de cada turma em cada disciplina. Cargas sem disciplina valem
+	// para a disciplina da própria turma.
+	turmaRepo := &TurmaRepository{DB: r.DB}
+	restante := make(map[chaveCarga]int)
+	total := make(map[chaveCarga]int)
+	disciplinas := make(map[int]models.Disciplina)
+	var ordemCargas []chaveCarga
+	for _, c := range cargas {
+		if c.HorasSemanais <= 0 {
+			return models.PlanoAlocacao{}, fmt.Errorf("%w: a carga horária da turma %d deve ser positiva", ErrGradeInvalida, c.TurmaID)
+		}
+		t, err := turmaRepo.GetByID(c.TurmaID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return models.PlanoAlocacao{}, fmt.Errorf("%w: turma %d não encontrada", ErrGradeInvalida, c.TurmaID)
+			}
+			return models.PlanoAlocacao{}, err
+		}
+
+		chave := chaveDaTurma(t)
+		if c.DisciplinaID != nil {
+			chave.disciplinaID = *c.DisciplinaID
+		}
+		if _, ok := disciplinas[chave.disciplinaID]; chave.disciplinaID != 0 && !ok {
+			d, err := getDisciplinaByID(r.DB, chave.disciplinaID)
+			if err == sql.ErrNoRows {
+				return models.PlanoAlocacao{}, fmt.Errorf("%w: disciplina %d não encontrada", ErrGradeInvalida, chave.disciplinaID)
+			}
+			if err != nil {
+				return models.PlanoAlocacao{}, err
+			}
+			disciplinas[d.ID] = d
+		}
+
+		if _, ok := total[chave]; !ok {
+			ordemCargas = append(ordemCargas, chave)
+		}
+		minutos := horasEmMinutos(c.HorasSemanais)
+		restante[chave] += minutos
+		total[chave] += minutos
+	}
+
+	// turmaNaDisciplina retorna a turma como ela é vista em uma aula da disciplina da carga,
+	// exigindo também os equipamentos da disciplina
+	turmaNaDisciplina := func(t models.Turma, chave chaveCarga) models.Turma {
+		if d, ok := disciplinas[chave.disciplinaID]; ok {
+			t.DisciplinaID = &d.ID
+			t.Disciplina = d.Nome
+			t.EquipamentosExigidos = somarEquipamentos(t.EquipamentosExigidos, d.EquipamentosExigidos)
+		}
+		return t
+	}
+
+	type aulaGerada struct {
+		janela   janelaAula
+		alocacao models.Alocacao
+	}
+
+	// Aulas já gravadas de cada professor, somadas às geradas pela grade para respeitar os limites de carga horária
+	aulasProfessor, err := aulasPorProfessor(r.DB, periodo)
+	if err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter aulas dos professores: %v", err)
+	}
+
+	var aulas []aulaGerada
+	professorDaCarga := make(map[chaveCarga]int)
+	motivos := make(map[chaveCarga]string)
+
+	pontuarContinuidade := func(p models.Professor, s models.Sala, t models.Turma) int {
+		pontuacao := pontuarCandidato(p, s, t)
+		if professorDaCarga[chaveDaTurma(t)] == p.ID {
+			pontuacao += bonusContinuidadeProfessor
+		}
+		return pontuacao
+	}
+
+	// Preferências dos professores, favorecidas em cada janela
+	preferencias, err := carregarPreferencias(r.DB)
+	if err != nil {
+		return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter preferências dos professores: %v", err)
+	}
+
+	for _, j := range janelas {
+		professores, err := r.getProfessoresDisponiveis(periodo, j.DiaSemana, j.HorarioInicio, j.HorarioFim)
+		if err != nil {
+			return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter professores disponíveis: %v", err)
+		}
+
+		salas, err := r.getSalasDisponiveis(periodo, j.DiaSemana, j.HorarioInicio, j.HorarioFim)
+		if err != nil {
+			return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter salas disponíveis: %v", err)
+		}
+
+		turmas, err := r.getTurmasDisponiveis(periodo, j.DiaSemana, j.HorarioInicio, j.HorarioFim)
+		if err != nil {
+			return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter turmas disponíveis: %v", err)
+		}
+
+		// Descartar os recursos já ocupados pela própria grade em janelas sobrepostas
+		professoresOcupados := make(map[int]bool)
+		salasOcupadas := make(map[int]bool)
+		turmasOcupadas := make(map[int]bool)
+		for _, aula := range aulas {
+			if aula.janela.SobrepoeA(j) {
+				professoresOcupados[aula.alocacao.ProfessorID] = true
+				salasOcupadas[aula.alocacao.SalaID] = true
+				turmasOcupadas[aula.alocacao.TurmaID] = true
+			}
+		}
+
+		var professoresLivres []models.Professor
+		for _, p := range professores {
+			if !professoresOcupados[p.ID] && len(violacoesCargaNasSemanas(p, aulasProfessor[p.ID], aulaProfessor{HorarioAula: j.HorarioAula}, periodo)) == 0 {
+				professoresLivres = append(professoresLivres, p)
+			}
+		}
+
+		var salasLivres []models.Sala
+		for _, s := range salas {
+			if !salasOcupadas[s.ID] {
+				salasLivres = append(salasLivres, s)
+			}
+		}
+
+		// Somente turmas com alguma disciplina cuja carga restante comporta a janela inteira. Entre
+		// elas, fica a disciplina com mais carga restante que algum professor livre leciona.
+		var turmasPendentes []models.Turma
+		for _, t := range turmas {
+			if turmasOcupadas[t.ID] {
+				continue
+			}
+
+			escolhida, atendida, ok := models.Turma{}, false, false
+			for _, chave := range ordemCargas {
+				if chave.turmaID != t.ID || restante[chave] < j.Duracao() {
+					continue
+				}
+
+				candidata := turmaNaDisciplina(t, chave)
+				candidataAtendida := professorAtendeAlgum(professoresLivres, candidata)
+				if !ok || (candidataAtendida && !atendida) ||
+					(candidataAtendida == atendida && restante[chave] > restante[chaveDaTurma(escolhida)]) {
+					escolhida, atendida, ok = candidata, candidataAtendida, true
+				}
+			}
+			if ok {
+				turmasPendentes = append(turmasPendentes, escolhida)
+			}
+		}
+
+		candidatos, naoAlocados := combinarRecursos(professoresLivres, salasLivres, turmasPendentes, preferencias.pontuar(j.HorarioAula, pontuarContinuidade))
+		for _, n := range naoAlocados {
+			if n.Tipo != "turma" {
+				continue
+			}
+			for _, t := range turmasPendentes {
+				if t.ID == n.ID {
+					motivos[chaveDaTurma(t)] = n.Motivo
+				}
+			}
+		}
+
+		for _, c := range candidatos {
+			aulas = append(aulas, aulaGerada{
+				janela: j,
+				alocacao: models.Alocacao{
+					ProfessorID:     c.Professor.ID,
+					SalaID:          c.Sala.ID,
+					TurmaID:         c.Turma.ID,
+					DisciplinaID:    c.Turma.DisciplinaID,
+					DiaSemana:       j.DiaSemana,
+					HorarioInicio:   j.HorarioInicio,
+					HorarioFim:      j.HorarioFim,
+					PeriodoLetivoID: periodo.ID,
+					Professor:       c.Professor,
+					Sala:            c.Sala,
+					Turma:           c.Turma,
+				},
+			})
+
+			chave := chaveDaTurma(c.Turma)
+			restante[chave] -= j.Duracao()
+			aulasProfessor[c.Professor.ID] = append(aulasProfessor[c.Professor.ID], aulaProfessor{HorarioAula: j.HorarioAula})
+			if _, ok := professorDaCarga[chave]; !ok {
+				professorDaCarga[chave] = c.Professor.ID
+			}
+		}
+	}
+
+	plano := models.PlanoAlocacao{
+		PeriodoLetivoID: periodo.ID,
+		Alocacoes:       []models.Alocacao{},
+		NaoAlocados:     []models.RecursoNaoAlocado{},
+	}
+	for _, aula := range aulas {
+		plano.Alocacoes = append(plano.Alocacoes, aula.alocacao)
+	}
+	plano.Satisfacao = preferencias.satisfacao(plano.Alocacoes)
+
+	// Relatar as turmas que não atingiram a carga horária semanal de alguma disciplina
+	for _, chave := range ordemCargas {
+		if restante[chave] <= 0 {
+			continue
+		}
+
+		motivo := fmt.Sprintf("faltaram %s das %s semanais", formatarHoras(restante[chave]), formatarHoras(total[chave]))
+		if d, ok := disciplinas[chave.disciplinaID]; ok {
+			motivo += " de " + d.Nome
+		}
+		if m, ok := motivos[chave]; ok {
+			motivo += ": " + m
+		} else {
+			motivo += ": nenhuma janela livre comporta a carga restante"
+		}
+
+		t, err := turmaRepo.GetByID(chave.turmaID)
+		if err != nil {
+			return models.PlanoAlocacao{}, fmt.Errorf("erro ao obter a turma %d: %v", chave.turmaID, err)
+		}
+		plano.NaoAlocados = append(plano.NaoAlocados, models.RecursoNaoAlocado{Tipo: "turma", ID: chave.turmaID, Nome: t.Nome, Motivo: motivo})
+	}
+
+	return plano, nil
+}
+
+// professorAtendeAlgum verifica se algum dos professores leciona a disciplina da turma
+func professorAtendeAlgum(professores []models.Professor, t models.Turma) bool {
+	return slices.ContainsFunc(professores, func(p models.Professor) bool { return professorAtendeTurma(p, t) })
+}
+
+// novaJanelaAula valida uma janela de aula informada na requisição
+func novaJanelaAula(h models.HorarioAula) (janelaAula, error) {
+	if h.DiaSemana.Numero() == 0 {
+		return janelaAula{}, fmt.Errorf("%w: dia_semana é obrigatório em todas as janelas", ErrGradeInvalida)
+	}
+
+	if err := models.ValidarHorarioAula(h.HorarioInicio, h.HorarioFim); err != nil {
+		return janelaAula{}, fmt.Errorf("%w: %v", ErrGradeInvalida, err)
+	}
+
+	return janelaAula{HorarioAula: h}, nil
+}
+
+// formatarHoras formata uma quantidade de minutos em horas, como "3h30"
+func formatarHoras(minutos int) string {
+	if minutos%60 == 0 {
+		return fmt.Sprintf("%dh", minutos/60)
+	}
+	return fmt.Sprintf("%dh%02d", minutos/60, minutos%60)
+}
@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrPeriodoLetivoInvalido indica que o período letivo informado é inválido ou não existe
+var ErrPeriodoLetivoInvalido = errors.New("dados do período letivo inválidos")
+
+// PeriodoLetivoRepository gerencia operações de banco de dados para períodos letivos
+type PeriodoLetivoRepository struct {
+	DB *sql.DB
+}
+
+// NewPeriodoLetivoRepository cria um novo repositório de períodos letivos
+func NewPeriodoLetivoRepository(db *sql.DB) *PeriodoLetivoRepository {
+	return &PeriodoLetivoRepository{DB: db}
+}
+
+// queryPeriodosLetivos retorna todos os períodos letivos em ordem cronológica, com o atual marcado
+func queryPeriodosLetivos(db executor) ([]models.PeriodoLetivo, error) {
+	rows, err := db.Query("SELECT id, nome, data_inicio, data_fim FROM periodos_letivos ORDER BY data_inicio")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periodos := []models.PeriodoLetivo{}
+	for rows.Next() {
+		var p models.PeriodoLetivo
+		if err := rows.Scan(&p.ID, &p.Nome, &p.DataInicio, &p.DataFim); err != nil {
+			return nil, err
+		}
+		periodos = append(periodos, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if i := indicePeriodoAtual(periodos, models.Hoje()); i >= 0 {
+		periodos[i].Atual = true
+	}
+	return periodos, nil
+}
+
+// indicePeriodoAtual retorna a posição do período letivo atual na lista ordenada por data: o que
+// contém a data de hoje ou, entre dois períodos, o próximo a começar ou, depois de todos, o último.
+// Retorna -1 se a lista estiver vazia.
+func indicePeriodoAtual(periodos []models.PeriodoLetivo, hoje models.Data) int {
+	for i, p := range periodos {
+		if !p.DataFim.Before(hoje.Time) {
+			return i
+		}
+	}
+	return len(periodos) - 1
+}
+
+// GetAll retorna todos os períodos letivos
+func (r *PeriodoLetivoRepository) GetAll() ([]models.PeriodoLetivo, error) {
+	return queryPeriodosLetivos(r.DB)
+}
+
+// GetByID retorna um período letivo pelo ID
+func (r *PeriodoLetivoRepository) GetByID(id int) (models.PeriodoLetivo, error) {
+	return getPeriodoLetivoByID(r.DB, id)
+}
+
+// GetAtual retorna o período letivo atual
+func (r *PeriodoLetivoRepository) GetAtual() (models.PeriodoLetivo, error) {
+	return resolverPeriodoLetivo(r.DB, 0)
+}
+
+// getPeriodoLetivoByID retorna um período letivo pelo ID usando a conexão ou transação informada
+func getPeriodoLetivoByID(db executor, id int) (models.PeriodoLetivo, error) {
+	periodos, err := queryPeriodosLetivos(db)
+	if err != nil {
+		return models.PeriodoLetivo{}, err
+	}
+	for _, p := range periodos {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return models.PeriodoLetivo{}, sql.ErrNoRows
+}
+
+// resolverPeriodoLetivo retorna o período letivo informado ou, com ID zero, o período letivo atual
+func resolverPeriodoLetivo(db executor, id int) (models.PeriodoLetivo, error) {
+	if id != 0 {
+		p, err := getPeriodoLetivoByID(db, id)
+		if err == sql.ErrNoRows {
+			return models.PeriodoLetivo{}, fmt.Errorf("%w: período letivo %d não encontrado", ErrPeriodoLetivoInvalido, id)
+		}
+		return p, err
+	}
+
+	periodos, err := queryPeriodosLetivos(db)
+	if err != nil {
+		return models.PeriodoLetivo{}, err
+	}
+	for _, p := range periodos {
+		if p.Atual {
+			return p, nil
+		}
+	}
+	return models.PeriodoLetivo{}, fmt.Errorf("%w: nenhum período letivo cadastrado", ErrPeriodoLetivoInvalido)
+}
+
+// periodosLetivosPorID retorna todos os períodos letivos indexados pelo ID
+func periodosLetivosPorID(db executor) (map[int]models.PeriodoLetivo, error) {
+	periodos, err := queryPeriodosLetivos(db)
+	if err != nil {
+		return nil, err
+	}
+
+	porID := make(map[int]models.PeriodoLetivo, len(periodos))
+	for _, p := range periodos {
+		porID[p.ID] = p
+	}
+	return porID, nil
+}
+
+// datasRestantes retorna o intervalo de datas em que as aulas do período letivo ainda acontecem:
+// do início do período, ou de hoje se ele já começou, até o fim. Em períodos já encerrados, o
+// início fica depois do fim.
+func datasRestantes(p models.PeriodoLetivo, hoje models.Data) (models.Data, models.Data) {
+	if p.DataInicio.Before(hoje.Time) {
+		return hoje, p.DataFim
+	}
+	return p.DataInicio, p.DataFim
+}
+
+// Create cria um novo período letivo
+func (r *PeriodoLetivoRepository) Create(p models.PeriodoLetivo) (models.PeriodoLetivo, error) {
+	if err := validarPeriodoLetivo(&p); err != nil {
+		return models.PeriodoLetivo{}, err
+	}
+
+	query := `INSERT INTO periodos_letivos (nome, data_inicio, data_fim) VALUES ($1, $2, $3) RETURNING id`
+
+	err := r.DB.QueryRow(query, p.Nome, p.DataInicio, p.DataFim).Scan(&p.ID)
+	if err != nil {
+		return models.PeriodoLetivo{}, traduzirErroPeriodoLetivo(err)
+	}
+
+	return getPeriodoLetivoByID(r.DB, p.ID)
+}
+
+// Update atualiza um período letivo existente
+func (r *PeriodoLetivoRepository) Update(p models.PeriodoLetivo) error {
+	if err := validarPeriodoLetivo(&p); err != nil {
+		return err
+	}
+
+	query := `UPDATE periodos_letivos SET nome = $1, data_inicio = $2, data_fim = $3 WHERE id = $4`
+
+	result, err := r.DB.Exec(query, p.Nome, p.DataInicio, p.DataFim, p.ID)
+	if err != nil {
+		return traduzirErroPeriodoLetivo(err)
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// Delete remove um período letivo pelo ID. Períodos letivos com alocações não podem ser removidos.
+func (r *PeriodoLetivoRepository) Delete(id int) error {
+	result, err := r.DB.Exec("DELETE FROM periodos_letivos WHERE id = $1", id)
+	if err != nil {
+		return traduzirErroPeriodoLetivo(err)
+	}
+	return exigirLinhaAfetada(result)
+}
+
+// validarPeriodoLetivo verifica se o período letivo tem nome e datas de início e fim válidas
+func validarPeriodoLetivo(p *models.PeriodoLetivo) error {
+	p.Nome = strings.TrimSpace(p.Nome)
+	if p.Nome == "" {
+		return fmt.Errorf("%w: nome é obrigatório", ErrPeriodoLetivoInvalido)
+	}
+	if p.DataInicio.IsZero() || p.DataFim.IsZero() {
+		return fmt.Errorf("%w: data_inicio e data_fim são obrigatórias", ErrPeriodoLetivoInvalido)
+	}
+	if p.DataFim.Before(p.DataInicio.Time) {
+		return fmt.Errorf("%w: data_fim deve ser igual ou posterior a data_inicio", ErrPeriodoLetivoInvalido)
+	}
+	return nil
+}
+
+// traduzirErroPeriodoLetivo converte nomes repetidos, períodos sobrepostos e a remoção de períodos
+// com alocações em ErrPeriodoLetivoInvalido
+func traduzirErroPeriodoLetivo(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch {
+	case pqErr.Code == codigoViolacaoUnicidade && pqErr.Constraint == "periodos_letivos_nome_unico":
+		return fmt.Errorf("%w: já existe um período letivo com este nome", ErrPeriodoLetivoInvalido)
+	case pqErr.Code == codigoViolacaoExclusao && pqErr.Constraint == "periodos_letivos_sem_sobreposicao":
+		return fmt.Errorf("%w: as datas se sobrepõem às de outro período letivo", ErrPeriodoLetivoInvalido)
+	case pqErr.Code == codigoViolacaoChaveEstrangeira && strings.Contains(pqErr.Constraint, "periodo_letivo_id"):
+		return fmt.Errorf("%w: o período letivo tem alocações", ErrPeriodoLetivoInvalido)
+	}
+	return err
+}
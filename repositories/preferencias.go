@@ -0,0 +1,271 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrPreferenciaInvalida indica que as preferências informadas são inválidas
+var ErrPreferenciaInvalida = errors.New("dados das preferências inválidos")
+
+// pesoPreferencia é o bônus na pontuação da alocação automática para cada preferência do professor
+// atendida. Ele supera a soma dos demais critérios (lugares ociosos, tipo da sala e continuidade do
+// professor na grade semanal), que assim só desempatam combinações que atendem as mesmas preferências.
+const pesoPreferencia = 1 << 20
+
+// PreferenciaRepository gerencia operações de banco de dados para as preferências dos professores
+type PreferenciaRepository struct {
+	DB *sql.DB
+}
+
+// NewPreferenciaRepository cria um novo repositório de preferências dos professores
+func NewPreferenciaRepository(db *sql.DB) *PreferenciaRepository {
+	return &PreferenciaRepository{DB: db}
+}
+
+// preferenciaSelectQuery é a consulta base das preferências dos professores
+const preferenciaSelectQuery = `
+	SELECT professor_id, dias_semana, turnos, blocos, sala_ids FROM preferencias_professor
+`
+
+// scanPreferencia lê uma linha da consulta base de preferências
+func scanPreferencia(row interface{ Scan(dest ...any) error }) (models.PreferenciaProfessor, error) {
+	var p models.PreferenciaProfessor
+	var salaIDs pq.Int64Array
+	err := row.Scan(&p.ProfessorID, pq.Array(&p.DiasSemana), pq.Array(&p.Turnos), pq.Array(&p.Blocos), &salaIDs)
+	if err != nil {
+		return models.PreferenciaProfessor{}, err
+	}
+
+	p.SalaIDs = idsDoArray(salaIDs)
+	return p, nil
+}
+
+// GetByProfessor retorna as preferências de um professor. Um professor sem preferências
+// cadastradas recebe listas vazias.
+func (r *PreferenciaRepository) GetByProfessor(professorID int) (models.PreferenciaProfessor, error) {
+	p, err := scanPreferencia(r.DB.QueryRow(preferenciaSelectQuery+" WHERE professor_id = $1", professorID))
+	if err == sql.ErrNoRows {
+		return models.PreferenciaProfessor{
+			ProfessorID: professorID,
+			DiasSemana:  []models.DiaSemana{},
+			Turnos:      []string{},
+			Blocos:      []string{},
+			SalaIDs:     []int{},
+		}, nil
+	}
+	return p, err
+}
+
+// Save grava as preferências de um professor, substituindo as anteriores
+func (r *PreferenciaRepository) Save(p models.PreferenciaProfessor) (models.PreferenciaProfessor, error) {
+	turnos, err := queryTurnos(r.DB)
+	if err != nil {
+		return models.PreferenciaProfessor{}, err
+	}
+	if err := validarPreferencia(&p, turnos); err != nil {
+		return models.PreferenciaProfessor{}, err
+	}
+
+	query := `
+		INSERT INTO preferencias_professor (professor_id, dias_semana, turnos, blocos, sala_ids)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (professor_id) DO UPDATE SET
+			dias_semana = EXCLUDED.dias_semana, turnos = EXCLUDED.turnos,
+			blocos = EXCLUDED.blocos, sala_ids = EXCLUDED.sala_ids
+	`
+	_, err = r.DB.Exec(query, p.ProfessorID, pq.Array(p.DiasSemana), pq.Array(p.Turnos), pq.Array(p.Blocos), arrayDeIDs(p.SalaIDs))
+	if err != nil {
+		return models.PreferenciaProfessor{}, err
+	}
+
+	return p, nil
+}
+
+// Delete remove as preferências de um professor
+func (r *PreferenciaRepository) Delete(professorID int) error {
+	_, err := r.DB.Exec("DELETE FROM preferencias_professor WHERE professor_id = $1", professorID)
+	return err
+}
+
+// validarPreferencia verifica se os turnos informados estão cadastrados, normaliza a grafia deles
+// e troca listas ausentes por listas vazias
+func validarPreferencia(p *models.PreferenciaProfessor, turnos []models.Turno) error {
+	for i, nome := range p.Turnos {
+		turno, ok := turnoPorNome(turnos, nome)
+		if !ok {
+			return fmt.Errorf("%w: turno %q não cadastrado", ErrPreferenciaInvalida, nome)
+		}
+		p.Turnos[i] = turno.Nome
+	}
+
+	if p.DiasSemana == nil {
+		p.DiasSemana = []models.DiaSemana{}
+	}
+	if p.Turnos == nil {
+		p.Turnos = []string{}
+	}
+	if p.Blocos == nil {
+		p.Blocos = []string{}
+	}
+	if p.SalaIDs == nil {
+		p.SalaIDs = []int{}
+	}
+	return nil
+}
+
+// turnoPorNome procura um turno pelo nome, sem diferenciar acentos e caixa
+func turnoPorNome(turnos []models.Turno, nome string) (models.Turno, bool) {
+	for _, t := range turnos {
+		if normalizarTexto(t.Nome) == normalizarTexto(nome) {
+			return t, true
+		}
+	}
+	return models.Turno{}, false
+}
+
+// contextoPreferencias reúne as preferências dos professores e os turnos usados para avaliá-las
+type contextoPreferencias struct {
+	preferencias map[int]models.PreferenciaProfessor
+	turnos       []models.Turno
+}
+
+// carregarPreferencias lê as preferências de todos os professores e os turnos cadastrados
+func carregarPreferencias(db executor) (contextoPreferencias, error) {
+	preferencias, err := preferenciasPorProfessor(db)
+	if err != nil {
+		return contextoPreferencias{}, err
+	}
+
+	turnos, err := queryTurnos(db)
+	if err != nil {
+		return contextoPreferencias{}, err
+	}
+
+	return contextoPreferencias{preferencias: preferencias, turnos: turnos}, nil
+}
+
+// preferenciasPorProfessor retorna as preferências de todos os professores que as cadastraram
+func preferenciasPorProfessor(db executor) (map[int]models.PreferenciaProfessor, error) {
+	rows, err := db.Query(preferenciaSelectQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	preferencias := make(map[int]models.PreferenciaProfessor)
+	for rows.Next() {
+		p, err := scanPreferencia(rows)
+		if err != nil {
+			return nil, err
+		}
+		preferencias[p.ProfessorID] = p
+	}
+
+	return preferencias, rows.Err()
+}
+
+// avaliarPreferencias retorna quantas das preferências do professor foram avaliadas para a aula
+// na sala informada e quantas delas a aula atende. Cada tipo de preferência cadastrada (dias,
+// turnos, blocos e salas) conta uma vez; a preferência de turno é atendida se a aula cabe na
+// janela de algum dos turnos preferidos.
+func (c contextoPreferencias) avaliarPreferencias(professorID int, aula models.HorarioAula, s models.Sala) (avaliadas, atendidas int) {
+	p := c.preferencias[professorID]
+	avaliar := func(atende bool) {
+		avaliadas++
+		if atende {
+			atendidas++
+		}
+	}
+
+	if len(p.DiasSemana) > 0 {
+		atende := false
+		for _, dia := range p.DiasSemana {
+			atende = atende || dia == aula.DiaSemana
+		}
+		avaliar(atende)
+	}
+
+	if len(p.Turnos) > 0 {
+		atende := false
+		for _, nome := range p.Turnos {
+			turno, ok := turnoPorNome(c.turnos, nome)
+			atende = atende || (ok && turnoComporta(turno, aula.HorarioInicio, aula.HorarioFim))
+		}
+		avaliar(atende)
+	}
+
+	if len(p.Blocos) > 0 {
+		atende := false
+		for _, b := range p.Blocos {
+			atende = atende || normalizarBloco(b) == normalizarBloco(s.Bloco)
+		}
+		avaliar(atende)
+	}
+
+	if len(p.SalaIDs) > 0 {
+		atende := false
+		for _, id := range p.SalaIDs {
+			atende = atende || id == s.ID
+		}
+		avaliar(atende)
+	}
+
+	return avaliadas, atendidas
+}
+
+// pontuar soma à pontuação de uma combinação o bônus das preferências do professor que ela atende
+func (c contextoPreferencias) pontuar(aula models.HorarioAula, pontuar funcaoPontuacao) funcaoPontuacao {
+	return func(p models.Professor, s models.Sala, t models.Turma) int {
+		_, atendidas := c.avaliarPreferencias(p.ID, aula, s)
+		return pontuar(p, s, t) + atendidas*pesoPreferencia
+	}
+}
+
+// satisfacao resume, para cada professor com alocações, o percentual das suas preferências
+// atendido pelas alocações informadas
+func (c contextoPreferencias) satisfacao(alocacoes []models.Alocacao) []models.SatisfacaoProfessor {
+	satisfacao := []models.SatisfacaoProfessor{}
+	indice := make(map[int]int)
+
+	for _, a := range alocacoes {
+		i, ok := indice[a.ProfessorID]
+		if !ok {
+			i = len(satisfacao)
+			indice[a.ProfessorID] = i
+			satisfacao = append(satisfacao, models.SatisfacaoProfessor{ProfessorID: a.ProfessorID, Nome: a.Professor.Nome})
+		}
+
+		aula := models.HorarioAula{DiaSemana: a.DiaSemana, HorarioInicio: a.HorarioInicio, HorarioFim: a.HorarioFim}
+		avaliadas, atendidas := c.avaliarPreferencias(a.ProfessorID, aula, a.Sala)
+		satisfacao[i].Aulas++
+		satisfacao[i].PreferenciasAvaliadas += avaliadas
+		satisfacao[i].PreferenciasAtendidas += atendidas
+	}
+
+	for i, s := range satisfacao {
+		satisfacao[i].Pontuacao = 100
+		if s.PreferenciasAvaliadas > 0 {
+			satisfacao[i].Pontuacao = math.Round(float64(s.PreferenciasAtendidas)/float64(s.PreferenciasAvaliadas)*1000) / 10
+		}
+	}
+
+	return satisfacao
+}
+
+// relatarSatisfacao preenche a satisfação dos professores com as alocações gravadas no resultado
+func (r *AlocacaoRepository) relatarSatisfacao(resultado models.ResultadoAlocacaoAutomatica) (models.ResultadoAlocacaoAutomatica, error) {
+	preferencias, err := carregarPreferencias(r.DB)
+	if err != nil {
+		return models.ResultadoAlocacaoAutomatica{}, err
+	}
+
+	resultado.Satisfacao = preferencias.satisfacao(resultado.Alocacoes)
+	return resultado, nil
+}
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/lib/pq"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// recorrenciaDasColunas monta a regra de recorrência lida do banco; com os valores padrão, a
+// alocação acontece toda semana e a regra é nil
+func recorrenciaDasColunas(intervalo int, inicio, fim *models.Data, semanas pq.Int64Array) *models.Recorrencia {
+	if intervalo <= 1 && inicio == nil && fim == nil && len(semanas) == 0 {
+		return nil
+	}
+	return &models.Recorrencia{IntervaloSemanas: intervalo, DataInicio: inicio, DataFim: fim, Semanas: idsDoArray(semanas)}
+}
+
+// colunasRecorrencia retorna os valores gravados nas colunas de recorrência de alocacoes
+func colunasRecorrencia(rec *models.Recorrencia) (int, *models.Data, *models.Data, pq.Int64Array) {
+	if rec == nil {
+		return 1, nil, nil, nil
+	}
+
+	var semanas pq.Int64Array
+	if len(rec.Semanas) > 0 {
+		semanas = arrayDeIDs(rec.Semanas)
+	}
+	return max(rec.IntervaloSemanas, 1), rec.DataInicio, rec.DataFim, semanas
+}
+
+// validarRecorrencia verifica se a regra de recorrência da alocação cabe no período letivo e tem
+// ao menos uma aula. As semanas são ordenadas e, se a regra não restringe nada, ela é descartada.
+func validarRecorrencia(a *models.Alocacao, p models.PeriodoLetivo) error {
+	if a.Recorrencia == nil {
+		return nil
+	}
+	rec := *a.Recorrencia
+	a.Recorrencia = &rec
+
+	if rec.IntervaloSemanas < 0 {
+		return fmt.Errorf("%w: intervalo_semanas deve ser positivo", ErrAlocacaoInvalida)
+	}
+	for _, limite := range []*models.Data{rec.DataInicio, rec.DataFim} {
+		if limite != nil && !dataNoPeriodo(*limite, p) {
+			return fmt.Errorf("%w: a data %s da recorrência está fora do período letivo %s (%s a %s)", ErrAlocacaoInvalida, limite, p.Nome, p.DataInicio, p.DataFim)
+		}
+	}
+	if rec.DataInicio != nil && rec.DataFim != nil && rec.DataFim.Before(rec.DataInicio.Time) {
+		return fmt.Errorf("%w: data_fim (%s) da recorrência não pode ser anterior a data_inicio (%s)", ErrAlocacaoInvalida, rec.DataFim, rec.DataInicio)
+	}
+
+	if len(rec.Semanas) > 0 {
+		if rec.IntervaloSemanas > 1 {
+			return fmt.Errorf("%w: informe intervalo_semanas ou semanas na recorrência, não os dois", ErrAlocacaoInvalida)
+		}
+
+		total := semanaDoPeriodo(p, p.DataFim)
+		semanas := make([]int, 0, len(rec.Semanas))
+		for _, s := range rec.Semanas {
+			if s < 1 || s > total {
+				return fmt.Errorf("%w: a semana %d não existe no período letivo %s, que tem %d semanas", ErrAlocacaoInvalida, s, p.Nome, total)
+			}
+			if !slices.Contains(semanas, s) {
+				semanas = append(semanas, s)
+			}
+		}
+		slices.Sort(semanas)
+		rec.Semanas = semanas
+	}
+
+	if rec.IntervaloSemanas <= 1 && rec.DataInicio == nil && rec.DataFim == nil && len(rec.Semanas) == 0 {
+		a.Recorrencia = nil
+		return nil
+	}
+	rec.IntervaloSemanas = max(rec.IntervaloSemanas, 1)
+
+	if len(datasDaAlocacao(*a, p)) == 0 {
+		return fmt.Errorf("%w: a recorrência não tem nenhuma aula de %s no período letivo %s", ErrAlocacaoInvalida, a.DiaSemana, p.Nome)
+	}
+	return nil
+}
+
+// semanaDoPeriodo retorna a semana do período letivo que contém a data, contada a partir de 1.
+// As semanas vão de segunda a domingo e a primeira é a que contém o início do período.
+func semanaDoPeriodo(p models.PeriodoLetivo, d models.Data) int {
+	primeiraSegunda := p.DataInicio.AdicionarDias(1 - p.DataInicio.DiaSemana().Numero())
+	dias := int(math.Round(d.Sub(primeiraSegunda.Time).Hours() / 24))
+	return dias/7 + 1
+}
+
+// periodoDaRecorrencia retorna o período letivo limitado às datas de início e fim da regra de recorrência
+func periodoDaRecorrencia(p models.PeriodoLetivo, rec *models.Recorrencia) models.PeriodoLetivo {
+	if rec == nil {
+		return p
+	}
+	if rec.DataInicio != nil && rec.DataInicio.After(p.DataInicio.Time) {
+		p.DataInicio = *rec.DataInicio
+	}
+	if rec.DataFim != nil && rec.DataFim.Before(p.DataFim.Time) {
+		p.DataFim = *rec.DataFim
+	}
+	return p
+}
+
+// datasDaAlocacao retorna, em ordem, as datas do período letivo em que a alocação tem aula de
+// acordo com a sua regra de recorrência. O intervalo de semanas conta a partir da primeira aula.
+func datasDaAlocacao(a models.Alocacao, p models.PeriodoLetivo) []models.Data {
+	limites := periodoDaRecorrencia(p, a.Recorrencia)
+
+	intervalo := 1
+	var semanas []int
+	if a.Recorrencia != nil {
+		intervalo = max(a.Recorrencia.IntervaloSemanas, 1)
+		semanas = a.Recorrencia.Semanas
+	}
+
+	var datas []models.Data
+	for d, n := proximaData(limites.DataInicio, a.DiaSemana), 0; !d.After(limites.DataFim.Time); d, n = d.AdicionarDias(7), n+1 {
+		if n%intervalo != 0 || (len(semanas) > 0 && !slices.Contains(semanas, semanaDoPeriodo(p, d))) {
+			continue
+		}
+		datas = append(datas, d)
+	}
+	return datas
+}
+
+// acontecemJuntas verifica se duas alocações do mesmo período letivo têm aula em alguma data em comum
+func acontecemJuntas(a, b models.Alocacao, p models.PeriodoLetivo) bool {
+	if a.Recorrencia == nil && b.Recorrencia == nil {
+		return a.DiaSemana == b.DiaSemana
+	}
+
+	datas := make(map[string]bool)
+	for _, d := range datasDaAlocacao(a, p) {
+		datas[d.String()] = true
+	}
+	for _, d := range datasDaAlocacao(b, p) {
+		if datas[d.String()] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,281 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ValidacaoError indica que uma alocação não atende a uma ou mais regras de alocação
+type ValidacaoError struct {
+	Violacoes []models.Violacao
+}
+
+func (e *ValidacaoError) Error() string {
+	mensagens := make([]string, 0, len(e.Violacoes))
+	for _, v := range e.Violacoes {
+		mensagens = append(mensagens, v.Mensagem)
+	}
+	return strings.Join(mensagens, "; ")
+}
+
+// recursosRegra reúne os dados da sala, da turma e do período letivo usados pelas regras de alocação
+type recursosRegra struct {
+	professor models.Professor
+	sala      models.Sala
+	turma     models.Turma
+	periodo   models.PeriodoLetivo
+}
+
+// regraAlocacao verifica uma regra sobre a alocação e retorna as violações encontradas
+type regraAlocacao func(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error)
+
+// regrasAlocacao são as regras verificadas ao criar ou atualizar uma alocação
+var regrasAlocacao = []regraAlocacao{
+	verificarCapacidade,
+	verificarTipoSala,
+	verificarDisponibilidadeProfessor,
+	verificarBloqueioSala,
+	verificarCargaProfessor,
+	verificarTurnoTurma,
+	verificarDisciplinaProfessor,
+	verificarDeslocamento,
+	verificarEquipamentos,
+}
+
+// validarRegras verifica todas as regras de alocação e reúne as violações em um único ValidacaoError
+func validarRegras(db executor, a models.Alocacao) error {
+	professor, err := getProfessorByID(db, a.ProfessorID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: professor %d não encontrado", ErrAlocacaoInvalida, a.ProfessorID)
+	}
+	if err != nil {
+		return err
+	}
+
+	sala, err := getSalaByID(db, a.SalaID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: sala %d não encontrada", ErrAlocacaoInvalida, a.SalaID)
+	}
+	if err != nil {
+		return err
+	}
+
+	turma, err := getTurmaByID(db, a.TurmaID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: turma %d não encontrada", ErrAlocacaoInvalida, a.TurmaID)
+	}
+	if err != nil {
+		return err
+	}
+
+	periodo, err := resolverPeriodoLetivo(db, a.PeriodoLetivoID)
+	if err != nil {
+		return err
+	}
+
+	recursos := recursosRegra{professor: professor, sala: sala, turma: turma, periodo: periodo}
+
+	var violacoes []models.Violacao
+	for _, regra := range regrasAlocacao {
+		v, err := regra(db, a, recursos)
+		if err != nil {
+			return err
+		}
+		violacoes = append(violacoes, v...)
+	}
+
+	if len(violacoes) > 0 {
+		return &ValidacaoError{Violacoes: violacoes}
+	}
+	return nil
+}
+
+// verificarCapacidade recusa turmas com mais alunos do que a capacidade da sala,
+// a menos que a alocação peça explicitamente para ignorar a capacidade
+func verificarCapacidade(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	if a.IgnorarCapacidade || r.turma.QuantAlunos <= r.sala.Capacidade {
+		return nil, nil
+	}
+
+	faltantes := r.turma.QuantAlunos - r.sala.Capacidade
+	return []models.Violacao{{
+		Regra:    "capacidade_insuficiente",
+		Mensagem: fmt.Sprintf("a sala comporta %d alunos e a turma tem %d: faltam %d lugares (envie ignorar_capacidade para alocar mesmo assim)", r.sala.Capacidade, r.turma.QuantAlunos, faltantes),
+		Detalhes: map[string]any{
+			"capacidade":        r.sala.Capacidade,
+			"quant_alunos":      r.turma.QuantAlunos,
+			"lugares_faltantes": faltantes,
+		},
+	}}, nil
+}
+
+// verificarTipoSala recusa salas de um tipo que a turma não exige nem aceita
+func verificarTipoSala(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	if tipoSalaAceito(r.sala.Tipo, r.turma) {
+		return nil, nil
+	}
+
+	return []models.Violacao{{
+		Regra:    "tipo_sala_incompativel",
+		Mensagem: fmt.Sprintf("a sala %s é do tipo %q e a turma %s exige sala do tipo %s", r.sala.Numero, r.sala.Tipo, r.turma.Nome, descreverTiposSala(r.turma)),
+		Detalhes: map[string]any{
+			"tipo_sala":   r.sala.Tipo,
+			"tipos_turma": tiposSalaDaTurma(r.turma),
+		},
+	}}, nil
+}
+
+// verificarDisponibilidadeProfessor recusa horários em que o professor está indisponível ou fora das
+// janelas em que declarou estar disponível
+func verificarDisponibilidadeProfessor(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	janelas, err := queryDisponibilidades(db, " WHERE professor_id = $1", a.ProfessorID)
+	if err != nil {
+		return nil, err
+	}
+
+	motivo := motivoIndisponibilidade(janelas, a.DiaSemana, a.HorarioInicio, a.HorarioFim)
+	if motivo == "" {
+		return nil, nil
+	}
+
+	return []models.Violacao{{
+		Regra:    "professor_indisponivel",
+		Mensagem: motivo,
+		Detalhes: map[string]any{
+			"professor_id":     a.ProfessorID,
+			"disponibilidades": janelas,
+		},
+	}}, nil
+}
+
+// verificarBloqueioSala recusa horários em que a sala está bloqueada em alguma das datas que restam
+// de aula da alocação no período letivo
+func verificarBloqueioSala(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	bloqueios, err := queryBloqueios(db, " WHERE sala_id = $1", a.SalaID)
+	if err != nil {
+		return nil, err
+	}
+
+	b, bloqueada := bloqueioQueAfeta(bloqueios, a, r.periodo)
+	if !bloqueada {
+		return nil, nil
+	}
+
+	return []models.Violacao{{
+		Regra:    "sala_bloqueada",
+		Mensagem: fmt.Sprintf("a sala %s está bloqueada %s", r.sala.Numero, descreverBloqueio(b)),
+		Detalhes: map[string]any{
+			"bloqueio": b,
+		},
+	}}, nil
+}
+
+// verificarCargaProfessor recusa alocações que ultrapassam os limites de carga horária do professor
+func verificarCargaProfessor(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	aulas, err := aulasDoProfessor(db, a.ProfessorID, r.periodo, a.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	nova := aulaProfessor{
+		HorarioAula: models.HorarioAula{DiaSemana: a.DiaSemana, HorarioInicio: a.HorarioInicio, HorarioFim: a.HorarioFim},
+		semanas:     semanasDaAlocacao(a, r.periodo),
+	}
+	return violacoesCargaNasSemanas(r.professor, aulas, nova, r.periodo), nil
+}
+
+// verificarTurnoTurma recusa aulas fora da janela de horário do turno da turma
+func verificarTurnoTurma(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	if r.turma.TurnoID == nil {
+		return nil, nil
+	}
+
+	turno, err := getTurnoByID(db, *r.turma.TurnoID)
+	if err != nil {
+		return nil, err
+	}
+
+	if turnoComporta(turno, a.HorarioInicio, a.HorarioFim) {
+		return nil, nil
+	}
+
+	return []models.Violacao{{
+		Regra: "fora_do_turno",
+		Mensagem: fmt.Sprintf("a turma %s é do turno %s (%s-%s) e a aula vai das %s às %s",
+			r.turma.Nome, turno.Nome, turno.HorarioInicio, turno.HorarioFim, a.HorarioInicio, a.HorarioFim),
+		Detalhes: map[string]any{
+			"turno": turno,
+		},
+	}}, nil
+}
+
+// verificarDisciplinaProfessor recusa alocações em que o professor não leciona a disciplina da aula.
+// A disciplina da aula é a informada na alocação ou, sem ela, a da turma. Professores sem nenhuma
+// disciplina associada não são verificados.
+func verificarDisciplinaProfessor(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	disciplinaID := a.DisciplinaID
+	if disciplinaID == nil {
+		disciplinaID = r.turma.DisciplinaID
+	}
+	if disciplinaID == nil {
+		return nil, nil
+	}
+
+	disciplina, err := getDisciplinaByID(db, *disciplinaID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: disciplina %d não encontrada", ErrAlocacaoInvalida, *disciplinaID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(r.professor.DisciplinaIDs) == 0 || professorLecionaDisciplina(r.professor, disciplina.ID) {
+		return nil, nil
+	}
+
+	return []models.Violacao{{
+		Regra:    "disciplina_nao_lecionada",
+		Mensagem: fmt.Sprintf("o professor %s não leciona %s", r.professor.Nome, disciplina.Nome),
+		Detalhes: map[string]any{
+			"disciplina":     disciplina,
+			"disciplina_ids": r.professor.DisciplinaIDs,
+		},
+	}}, nil
+}
+
+// verificarDeslocamento recusa aulas em um bloco que o professor ou a turma não conseguem alcançar
+// no intervalo desde a aula anterior, ou até a seguinte, no mesmo dia
+func verificarDeslocamento(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	violacoes, _, err := analisarDeslocamentos(db, a, r.sala)
+	return violacoes, err
+}
+
+// verificarEquipamentos recusa salas que não têm os equipamentos exigidos pela turma ou pela
+// disciplina da aula
+func verificarEquipamentos(db executor, a models.Alocacao, r recursosRegra) ([]models.Violacao, error) {
+	exigidos, err := equipamentosDaAula(db, r.turma, a.DisciplinaID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: disciplina da aula não encontrada", ErrAlocacaoInvalida)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	faltantes := equipamentosFaltantes(r.sala.Equipamentos, exigidos)
+	if len(faltantes) == 0 {
+		return nil, nil
+	}
+
+	return []models.Violacao{{
+		Regra:    "equipamentos_insuficientes",
+		Mensagem: fmt.Sprintf("a sala %s não tem os equipamentos exigidos pela aula: faltam %s", r.sala.Numero, strings.Join(faltantes, ", ")),
+		Detalhes: map[string]any{
+			"equipamentos":          r.sala.Equipamentos,
+			"equipamentos_exigidos": exigidos,
+			"faltantes":             faltantes,
+		},
+	}}, nil
+}
@@ -3,8 +3,8 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
-	"math/rand"
-	"time"
+
+	"github.com/lib/pq"
 
 	"github.com/cristiantebaldi/class-organize-api/models"
 )
@@ -29,6 +29,13 @@ type AlocacaoRepository struct {
 	DB *sql.DB
 }
 
+// executor abstrai *sql.DB e *sql.Tx para que as operações possam rodar dentro ou fora de uma transação
+type executor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 // NewProfessorRepository cria um novo repositório de professores
 func NewProfessorRepository(db *sql.DB) *ProfessorRepository {
 	return &ProfessorRepository{DB: db}
@@ -51,9 +58,30 @@ func NewAlocacaoRepository(db *sql.DB) *AlocacaoRepository {
 
 // ===== Métodos do ProfessorRepository =====
 
+// disciplinasProfessorColuna lista os IDs das disciplinas do professor de alias p
+const disciplinasProfessorColuna = `COALESCE((SELECT ARRAY_AGG(pd.disciplina_id ORDER BY pd.disciplina_id)
+	FROM professor_disciplinas pd WHERE pd.professor_id = p.id), '{}')`
+
+// professorSelectQuery é a consulta base de professores
+const professorSelectQuery = `SELECT p.id, p.nome, p.email, p.formacao, p.disciplina, ` + disciplinasProfessorColuna + `,
+	p.max_horas_semanais, p.max_horas_diarias, p.max_horas_consecutivas, p.descanso_minimo FROM professores p`
+
+// scanProfessor lê uma linha da consulta base de professores
+func scanProfessor(row interface{ Scan(dest ...any) error }) (models.Professor, error) {
+	var p models.Professor
+	var disciplinaIDs pq.Int64Array
+	err := row.Scan(&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina, &disciplinaIDs,
+		&p.MaxHorasSemanais, &p.MaxHorasDiarias, &p.MaxHorasConsecutivas, &p.DescansoMinimo)
+	if err != nil {
+		return models.Professor{}, err
+	}
+	p.DisciplinaIDs = idsDoArray(disciplinaIDs)
+	return p, nil
+}
+
 // GetAll retorna todos os professores
 func (r *ProfessorRepository) GetAll() ([]models.Professor, error) {
-	rows, err := r.DB.Query("SELECT id, nome, email, formacao, disciplina FROM professores")
+	rows, err := r.DB.Query(professorSelectQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -61,8 +89,7 @@ func (r *ProfessorRepository) GetAll() ([]models.Professor, error) {
 
 	var professores []models.Professor
 	for rows.Next() {
-		var p models.Professor
-		err := rows.Scan(&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina)
+		p, err := scanProfessor(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -74,36 +101,75 @@ func (r *ProfessorRepository) GetAll() ([]models.Professor, error) {
 
 // GetByID retorna um professor pelo ID
 func (r *ProfessorRepository) GetByID(id int) (models.Professor, error) {
-	var p models.Professor
-	err := r.DB.QueryRow("SELECT id, nome, email, formacao, disciplina FROM professores WHERE id = $1", id).Scan(
-		&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina,
-	)
+	return getProfessorByID(r.DB, id)
+}
+
+// getProfessorByID retorna um professor pelo ID usando a conexão ou transação informada
+func getProfessorByID(db executor, id int) (models.Professor, error) {
+	return scanProfessor(db.QueryRow(professorSelectQuery+" WHERE p.id = $1", id))
+}
+
+// Create cria um novo professor. Se disciplina_ids for informado, o professor é associado a essas
+// disciplinas; caso contrário, à disciplina cadastrada com o nome informado em disciplina, se houver.
+func (r *ProfessorRepository) Create(p models.Professor) (models.Professor, error) {
+	tx, err := r.DB.Begin()
 	if err != nil {
 		return models.Professor{}, err
 	}
-	return p, nil
-}
+	defer tx.Rollback()
 
-// Create cria um novo professor
-func (r *ProfessorRepository) Create(p models.Professor) (models.Professor, error) {
-	query := `INSERT INTO professores (nome, email, formacao, disciplina) 
-			VALUES ($1, $2, $3, $4) RETURNING id`
+	query := `INSERT INTO professores (nome, email, formacao, disciplina, 
+			max_horas_semanais, max_horas_diarias, max_horas_consecutivas, descanso_minimo) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
 
-	err := r.DB.QueryRow(query, p.Nome, p.Email, p.Formacao, p.Disciplina).Scan(&p.ID)
+	err = tx.QueryRow(query, p.Nome, p.Email, p.Formacao, p.Disciplina,
+		p.MaxHorasSemanais, p.MaxHorasDiarias, p.MaxHorasConsecutivas, p.DescansoMinimo).Scan(&p.ID)
 	if err != nil {
 		return models.Professor{}, err
 	}
 
-	return p, nil
+	if p.DisciplinaIDs == nil {
+		_, err = tx.Exec(`INSERT INTO professor_disciplinas (professor_id, disciplina_id)
+			SELECT $1, id FROM disciplinas WHERE LOWER(nome) = LOWER(TRIM($2))`, p.ID, p.Disciplina)
+	} else {
+		err = gravarDisciplinasProfessor(tx, p.ID, p.DisciplinaIDs)
+	}
+	if err != nil {
+		return models.Professor{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Professor{}, err
+	}
+	return getProfessorByID(r.DB, p.ID)
 }
 
-// Update atualiza um professor existente
+// Update atualiza um professor existente. As disciplinas do professor só são substituídas se
+// disciplina_ids for informado.
 func (r *ProfessorRepository) Update(p models.Professor) error {
-	query := `UPDATE professores SET nome = $1, email = $2, formacao = $3, disciplina = $4 
-			WHERE id = $5`
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	_, err := r.DB.Exec(query, p.Nome, p.Email, p.Formacao, p.Disciplina, p.ID)
-	return err
+	query := `UPDATE professores SET nome = $1, email = $2, formacao = $3, disciplina = $4, 
+			max_horas_semanais = $5, max_horas_diarias = $6, max_horas_consecutivas = $7, descanso_minimo = $8 
+			WHERE id = $9`
+
+	_, err = tx.Exec(query, p.Nome, p.Email, p.Formacao, p.Disciplina,
+		p.MaxHorasSemanais, p.MaxHorasDiarias, p.MaxHorasConsecutivas, p.DescansoMinimo, p.ID)
+	if err != nil {
+		return err
+	}
+
+	if p.DisciplinaIDs != nil {
+		if err := gravarDisciplinasProfessor(tx, p.ID, p.DisciplinaIDs); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 // Delete remove um professor pelo ID
@@ -114,9 +180,23 @@ func (r *ProfessorRepository) Delete(id int) error {
 
 // ===== Métodos do SalaRepository =====
 
+// salaSelectQuery é a consulta base de salas
+const salaSelectQuery = "SELECT id, numero, capacidade, bloco, tipo, bloco_id, computadores, projetor, bancadas, acessibilidade FROM salas"
+
+// scanSala lê uma linha da consulta base de salas
+func scanSala(row interface{ Scan(dest ...any) error }) (models.Sala, error) {
+	var s models.Sala
+	err := row.Scan(&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo, &s.BlocoID,
+		&s.Equipamentos.Computadores, &s.Equipamentos.Projetor, &s.Equipamentos.Bancadas, pq.Array(&s.Equipamentos.Acessibilidade))
+	if err != nil {
+		return models.Sala{}, err
+	}
+	return s, nil
+}
+
 // GetAll retorna todas as salas
 func (r *SalaRepository) GetAll() ([]models.Sala, error) {
-	rows, err := r.DB.Query("SELECT id, numero, capacidade, bloco, tipo FROM salas")
+	rows, err := r.DB.Query(salaSelectQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -124,8 +204,7 @@ func (r *SalaRepository) GetAll() ([]models.Sala, error) {
 
 	var salas []models.Sala
 	for rows.Next() {
-		var s models.Sala
-		err := rows.Scan(&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo)
+		s, err := scanSala(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -137,36 +216,52 @@ func (r *SalaRepository) GetAll() ([]models.Sala, error) {
 
 // GetByID retorna uma sala pelo ID
 func (r *SalaRepository) GetByID(id int) (models.Sala, error) {
-	var s models.Sala
-	err := r.DB.QueryRow("SELECT id, numero, capacidade, bloco, tipo FROM salas WHERE id = $1", id).Scan(
-		&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo,
-	)
-	if err != nil {
-		return models.Sala{}, err
-	}
-	return s, nil
+	return getSalaByID(r.DB, id)
 }
 
+// getSalaByID retorna uma sala pelo ID usando a conexão ou transação informada
+func getSalaByID(db executor, id int) (models.Sala, error) {
+	return scanSala(db.QueryRow(salaSelectQuery+" WHERE id = $1", id))
+}
+
+// salaValores são os valores gravados de uma sala. O bloco é resolvido a partir do ID ($5) ou do
+// nome ($3), mantendo o nome e o ID coerentes.
+const salaValores = `$1, $2, $4, $6, $7, $8, COALESCE($9::TEXT[], '{}'),
+	COALESCE(NULLIF(TRIM($3), ''), (SELECT nome FROM blocos WHERE id = $5), ''),
+	COALESCE($5::INT, (SELECT id FROM blocos
+		WHERE REGEXP_REPLACE(LOWER(TRIM(nome)), '^bloco\s+', '') = REGEXP_REPLACE(LOWER(TRIM($3)), '^bloco\s+', '')))`
+
+// salaColunas são as colunas gravadas de uma sala, na ordem de salaValores
+const salaColunas = `numero, capacidade, tipo, computadores, projetor, bancadas, acessibilidade, bloco, bloco_id`
+
 // Create cria uma nova sala
 func (r *SalaRepository) Create(s models.Sala) (models.Sala, error) {
-	query := `INSERT INTO salas (numero, capacidade, bloco, tipo) 
-			VALUES ($1, $2, $3, $4) RETURNING id`
+	if err := validarEquipamentos(&s.Equipamentos); err != nil {
+		return models.Sala{}, err
+	}
+
+	query := `INSERT INTO salas (` + salaColunas + `) VALUES (` + salaValores + `) RETURNING id`
 
-	err := r.DB.QueryRow(query, s.Numero, s.Capacidade, s.Bloco, s.Tipo).Scan(&s.ID)
+	err := r.DB.QueryRow(query, s.Numero, s.Capacidade, s.Bloco, s.Tipo, s.BlocoID,
+		s.Equipamentos.Computadores, s.Equipamentos.Projetor, s.Equipamentos.Bancadas, pq.Array(s.Equipamentos.Acessibilidade)).Scan(&s.ID)
 	if err != nil {
-		return models.Sala{}, err
+		return models.Sala{}, traduzirErroBloco(err)
 	}
 
-	return s, nil
+	return getSalaByID(r.DB, s.ID)
 }
 
 // Update atualiza uma sala existente
 func (r *SalaRepository) Update(s models.Sala) error {
-	query := `UPDATE salas SET numero = $1, capacidade = $2, bloco = $3, tipo = $4 
-			WHERE id = $5`
+	if err := validarEquipamentos(&s.Equipamentos); err != nil {
+		return err
+	}
 
-	_, err := r.DB.Exec(query, s.Numero, s.Capacidade, s.Bloco, s.Tipo, s.ID)
-	return err
+	query := `UPDATE salas SET (` + salaColunas + `) = (` + salaValores + `) WHERE id = $10`
+
+	_, err := r.DB.Exec(query, s.Numero, s.Capacidade, s.Bloco, s.Tipo, s.BlocoID,
+		s.Equipamentos.Computadores, s.Equipamentos.Projetor, s.Equipamentos.Bancadas, pq.Array(s.Equipamentos.Acessibilidade), s.ID)
+	return traduzirErroBloco(err)
 }
 
 // Delete remove uma sala pelo ID
@@ -177,9 +272,25 @@ func (r *SalaRepository) Delete(id int) error {
 
 // ===== Métodos do TurmaRepository =====
 
+// turmaSelectQuery é a consulta base de turmas
+const turmaSelectQuery = `SELECT id, nome, curso, periodo, quant_alunos, disciplina, tipo_sala, tipos_sala_aceitos, turno_id, disciplina_id, curso_id, semestre,
+	exige_computadores, exige_projetor, exige_bancadas, exige_acessibilidade FROM turmas`
+
+// scanTurma lê uma linha da consulta base de turmas
+func scanTurma(row interface{ Scan(dest ...any) error }) (models.Turma, error) {
+	var t models.Turma
+	err := row.Scan(&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos, &t.Disciplina, &t.TipoSala, pq.Array(&t.TiposSalaAceitos), &t.TurnoID,
+		&t.DisciplinaID, &t.CursoID, &t.Semestre, &t.EquipamentosExigidos.Computadores, &t.EquipamentosExigidos.Projetor,
+		&t.EquipamentosExigidos.Bancadas, pq.Array(&t.EquipamentosExigidos.Acessibilidade))
+	if err != nil {
+		return models.Turma{}, err
+	}
+	return t, nil
+}
+
 // GetAll retorna todas as turmas
 func (r *TurmaRepository) GetAll() ([]models.Turma, error) {
-	rows, err := r.DB.Query("SELECT id, nome, curso, periodo, quant_alunos FROM turmas")
+	rows, err := r.DB.Query(turmaSelectQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -187,8 +298,7 @@ func (r *TurmaRepository) GetAll() ([]models.Turma, error) {
 
 	var turmas []models.Turma
 	for rows.Next() {
-		var t models.Turma
-		err := rows.Scan(&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos)
+		t, err := scanTurma(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -200,36 +310,63 @@ func (r *TurmaRepository) GetAll() ([]models.Turma, error) {
 
 // GetByID retorna uma turma pelo ID
 func (r *TurmaRepository) GetByID(id int) (models.Turma, error) {
-	var t models.Turma
-	err := r.DB.QueryRow("SELECT id, nome, curso, periodo, quant_alunos FROM turmas WHERE id = $1", id).Scan(
-		&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos,
-	)
-	if err != nil {
-		return models.Turma{}, err
-	}
-	return t, nil
+	return getTurmaByID(r.DB, id)
+}
+
+// getTurmaByID retorna uma turma pelo ID usando a conexão ou transação informada
+func getTurmaByID(db executor, id int) (models.Turma, error) {
+	return scanTurma(db.QueryRow(turmaSelectQuery+" WHERE id = $1", id))
 }
 
+// turmaValores são os valores gravados de uma turma. A disciplina e o curso são resolvidos a partir
+// do ID ($9 e $10) ou do nome ($5 e $2), mantendo o nome e o ID de cada um coerentes.
+const turmaValores = `$1, $3, $4, $6, COALESCE($7::TEXT[], '{}'), $8, $11, $12, $13, $14, COALESCE($15::TEXT[], '{}'),
+	COALESCE(NULLIF(TRIM($5), ''), (SELECT nome FROM disciplinas WHERE id = $9), ''),
+	COALESCE($9::INT, (SELECT id FROM disciplinas WHERE LOWER(nome) = LOWER(TRIM($5)))),
+	COALESCE(NULLIF(TRIM($2), ''), (SELECT nome FROM cursos WHERE id = $10), ''),
+	COALESCE($10::INT, (SELECT id FROM cursos WHERE LOWER(nome) = LOWER(TRIM($2))))`
+
+// turmaColunas são as colunas gravadas de uma turma, na ordem de turmaValores
+const turmaColunas = `nome, periodo, quant_alunos, tipo_sala, tipos_sala_aceitos, turno_id, semestre,
+	exige_computadores, exige_projetor, exige_bancadas, exige_acessibilidade,
+	disciplina, disciplina_id, curso, curso_id`
+
 // Create cria uma nova turma
 func (r *TurmaRepository) Create(t models.Turma) (models.Turma, error) {
-	query := `INSERT INTO turmas (nome, curso, periodo, quant_alunos) 
-			VALUES ($1, $2, $3, $4) RETURNING id`
+	if err := validarEquipamentos(&t.EquipamentosExigidos); err != nil {
+		return models.Turma{}, err
+	}
 
-	err := r.DB.QueryRow(query, t.Nome, t.Curso, t.Periodo, t.QuantAlunos).Scan(&t.ID)
+	query := `INSERT INTO turmas (` + turmaColunas + `) VALUES (` + turmaValores + `) RETURNING id`
+
+	err := r.DB.QueryRow(query, t.Nome, t.Curso, t.Periodo, t.QuantAlunos, t.Disciplina, t.TipoSala, pq.Array(t.TiposSalaAceitos),
+		t.TurnoID, t.DisciplinaID, t.CursoID, t.Semestre, t.EquipamentosExigidos.Computadores, t.EquipamentosExigidos.Projetor,
+		t.EquipamentosExigidos.Bancadas, pq.Array(t.EquipamentosExigidos.Acessibilidade)).Scan(&t.ID)
 	if err != nil {
-		return models.Turma{}, err
+		return models.Turma{}, traduzirErroTurma(err)
 	}
 
-	return t, nil
+	return getTurmaByID(r.DB, t.ID)
 }
 
 // Update atualiza uma turma existente
 func (r *TurmaRepository) Update(t models.Turma) error {
-	query := `UPDATE turmas SET nome = $1, curso = $2, periodo = $3, quant_alunos = $4 
-			WHERE id = $5`
+	if err := validarEquipamentos(&t.EquipamentosExigidos); err != nil {
+		return err
+	}
 
-	_, err := r.DB.Exec(query, t.Nome, t.Curso, t.Periodo, t.QuantAlunos, t.ID)
-	return err
+	query := `UPDATE turmas SET (` + turmaColunas + `) = (` + turmaValores + `) WHERE id = $16`
+
+	_, err := r.DB.Exec(query, t.Nome, t.Curso, t.Periodo, t.QuantAlunos, t.Disciplina, t.TipoSala, pq.Array(t.TiposSalaAceitos),
+		t.TurnoID, t.DisciplinaID, t.CursoID, t.Semestre, t.EquipamentosExigidos.Computadores, t.EquipamentosExigidos.Projetor,
+		t.EquipamentosExigidos.Bancadas, pq.Array(t.EquipamentosExigidos.Acessibilidade), t.ID)
+	return traduzirErroTurma(err)
+}
+
+// GetCargaHoraria retorna a carga horária semanal de cada disciplina da turma, segundo a grade
+// curricular do seu curso no seu semestre
+func (r *TurmaRepository) GetCargaHoraria(turmaID int) ([]models.CargaHorariaTurma, error) {
+	return cargasCurriculares(r.DB, " WHERE t.id = $1", turmaID)
 }
 
 // Delete remove uma turma pelo ID
@@ -240,21 +377,68 @@ func (r *TurmaRepository) Delete(id int) error {
 
 // ===== Métodos do AlocacaoRepository =====
 
-// GetAll retorna todas as alocações com detalhes
-func (r *AlocacaoRepository) GetAll() ([]models.Alocacao, error) {
-	query := `
-		SELECT 
-			a.id, a.professor_id, a.sala_id, a.turma_id, a.dia_semana, a.horario_inicio, a.horario_fim,
-			p.id, p.nome, p.email, p.formacao, p.disciplina,
-			s.id, s.numero, s.capacidade, s.bloco, s.tipo,
-			t.id, t.nome, t.curso, t.periodo, t.quant_alunos
-		FROM alocacoes a
-		JOIN professores p ON a.professor_id = p.id
-		JOIN salas s ON a.sala_id = s.id
-		JOIN turmas t ON a.turma_id = t.id
-	`
+// alocacaoSelectQuery é a consulta base de alocações com os detalhes de professor, sala e turma
+const alocacaoSelectQuery = `
+	SELECT 
+		a.id, a.professor_id, a.sala_id, a.turma_id, a.dia_semana, a.horario_inicio, a.horario_fim, a.ignorar_capacidade, a.bloqueio_sala_id,
+		a.periodo_letivo_id, a.intervalo_semanas, a.data_inicio, a.data_fim, a.semanas, a.disciplina_id, COALESCE(d.nome, ''), COALESCE(d.codigo, ''),
+		COALESCE(d.exige_computadores, 0), COALESCE(d.exige_projetor, FALSE), COALESCE(d.exige_bancadas, 0), COALESCE(d.exige_acessibilidade, '{}'),
+		p.id, p.nome, p.email, p.formacao, p.disciplina, ` + disciplinasProfessorColuna + `,
+		p.max_horas_semanais, p.max_horas_diarias, p.max_horas_consecutivas, p.descanso_minimo,
+		s.id, s.numero, s.capacidade, s.bloco, s.tipo, s.bloco_id, s.computadores, s.projetor, s.bancadas, s.acessibilidade,
+		t.id, t.nome, t.curso, t.periodo, t.quant_alunos, t.disciplina, t.tipo_sala, t.tipos_sala_aceitos, t.turno_id, t.disciplina_id, t.curso_id, t.semestre,
+		t.exige_computadores, t.exige_projetor, t.exige_bancadas, t.exige_acessibilidade
+	FROM alocacoes a
+	JOIN professores p ON a.professor_id = p.id
+	JOIN salas s ON a.sala_id = s.id
+	JOIN turmas t ON a.turma_id = t.id
+	LEFT JOIN disciplinas d ON a.disciplina_id = d.id
+`
+
+// scanAlocacao lê uma linha da consulta base de alocações
+func scanAlocacao(row interface{ Scan(dest ...any) error }) (models.Alocacao, error) {
+	var a models.Alocacao
+	var p models.Professor
+	var s models.Sala
+	var t models.Turma
+	var d models.Disciplina
+	var disciplinaIDs pq.Int64Array
+	var intervalo int
+	var recorrenciaInicio, recorrenciaFim *models.Data
+	var semanas pq.Int64Array
+
+	err := row.Scan(
+		&a.ID, &a.ProfessorID, &a.SalaID, &a.TurmaID, &a.DiaSemana, &a.HorarioInicio, &a.HorarioFim, &a.IgnorarCapacidade, &a.BloqueioSalaID,
+		&a.PeriodoLetivoID, &intervalo, &recorrenciaInicio, &recorrenciaFim, &semanas, &a.DisciplinaID, &d.Nome, &d.Codigo,
+		&d.EquipamentosExigidos.Computadores, &d.EquipamentosExigidos.Projetor, &d.EquipamentosExigidos.Bancadas, pq.Array(&d.EquipamentosExigidos.Acessibilidade),
+		&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina, &disciplinaIDs,
+		&p.MaxHorasSemanais, &p.MaxHorasDiarias, &p.MaxHorasConsecutivas, &p.DescansoMinimo,
+		&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo, &s.BlocoID,
+		&s.Equipamentos.Computadores, &s.Equipamentos.Projetor, &s.Equipamentos.Bancadas, pq.Array(&s.Equipamentos.Acessibilidade),
+		&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos, &t.Disciplina, &t.TipoSala, pq.Array(&t.TiposSalaAceitos), &t.TurnoID, &t.DisciplinaID, &t.CursoID, &t.Semestre,
+		&t.EquipamentosExigidos.Computadores, &t.EquipamentosExigidos.Projetor, &t.EquipamentosExigidos.Bancadas, pq.Array(&t.EquipamentosExigidos.Acessibilidade),
+	)
+	if err != nil {
+		return models.Alocacao{}, err
+	}
 
-	rows, err := r.DB.Query(query)
+	a.Recorrencia = recorrenciaDasColunas(intervalo, recorrenciaInicio, recorrenciaFim, semanas)
+	if a.DisciplinaID != nil {
+		d.ID = *a.DisciplinaID
+		a.Disciplina = &d
+	}
+	p.DisciplinaIDs = idsDoArray(disciplinaIDs)
+	a.Professor = p
+	a.Sala = s
+	a.Turma = t
+	a.LugaresFaltantes = max(0, t.QuantAlunos-s.Capacidade)
+
+	return a, nil
+}
+
+// queryAlocacoes executa a consulta base de alocações com o filtro informado, usando a conexão ou transação informada
+func queryAlocacoes(db executor, filtro string, args ...any) ([]models.Alocacao, error) {
+	rows, err := db.Query(alocacaoSelectQuery+filtro, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -262,364 +446,233 @@ func (r *AlocacaoRepository) GetAll() ([]models.Alocacao, error) {
 
 	var alocacoes []models.Alocacao
 	for rows.Next() {
-		var a models.Alocacao
-		var p models.Professor
-		var s models.Sala
-		var t models.Turma
-
-		err := rows.Scan(
-			&a.ID, &a.ProfessorID, &a.SalaID, &a.TurmaID, &a.DiaSemana, &a.HorarioInicio, &a.HorarioFim,
-			&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina,
-			&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo,
-			&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos,
-		)
+		a, err := scanAlocacao(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		a.Professor = p
-		a.Sala = s
-		a.Turma = t
 		alocacoes = append(alocacoes, a)
 	}
 
 	return alocacoes, nil
 }
 
+// queryAlocacoesDoPeriodo executa a consulta base de alocações restrita ao período letivo informado
+// (zero para o atual), com o filtro adicional informado. O ID do período letivo é o último argumento.
+func queryAlocacoesDoPeriodo(db executor, periodoLetivoID int, filtro string, args ...any) ([]models.Alocacao, error) {
+	periodo, err := resolverPeriodoLetivo(db, periodoLetivoID)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, periodo.ID)
+	return queryAlocacoes(db, fmt.Sprintf(" WHERE a.periodo_letivo_id = $%d%s", len(args), filtro), args...)
+}
+
+// GetAll retorna todas as alocações do período letivo informado (zero para o atual) com detalhes
+func (r *AlocacaoRepository) GetAll(periodoLetivoID int) ([]models.Alocacao, error) {
+	return queryAlocacoesDoPeriodo(r.DB, periodoLetivoID, "")
+}
+
 // GetByID retorna uma alocação pelo ID com detalhes
 func (r *AlocacaoRepository) GetByID(id int) (models.Alocacao, error) {
-	query := `
-		SELECT 
-			a.id, a.professor_id, a.sala_id, a.turma_id, a.dia_semana, a.horario_inicio, a.horario_fim,
-			p.id, p.nome, p.email, p.formacao, p.disciplina,
-			s.id, s.numero, s.capacidade, s.bloco, s.tipo,
-			t.id, t.nome, t.curso, t.periodo, t.quant_alunos
-		FROM alocacoes a
-		JOIN professores p ON a.professor_id = p.id
-		JOIN salas s ON a.sala_id = s.id
-		JOIN turmas t ON a.turma_id = t.id
-		WHERE a.id = $1
-	`
+	return getAlocacaoByID(r.DB, id)
+}
 
-	var a models.Alocacao
-	var p models.Professor
-	var s models.Sala
-	var t models.Turma
+// getAlocacaoByID retorna uma alocação pelo ID usando a conexão ou transação informada
+func getAlocacaoByID(db executor, id int) (models.Alocacao, error) {
+	return scanAlocacao(db.QueryRow(alocacaoSelectQuery+" WHERE a.id = $1", id))
+}
 
-	err := r.DB.QueryRow(query, id).Scan(
-		&a.ID, &a.ProfessorID, &a.SalaID, &a.TurmaID, &a.DiaSemana, &a.HorarioInicio, &a.HorarioFim,
-		&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina,
-		&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo,
-		&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos,
-	)
+// Create cria uma nova alocação. A verificação de disponibilidade e a gravação acontecem na
+// mesma transação, com os recursos bloqueados, para que requisições simultâneas não gravem
+// alocações conflitantes.
+func (r *AlocacaoRepository) Create(a models.Alocacao) (models.Alocacao, error) {
+	tx, err := r.DB.Begin()
 	if err != nil {
 		return models.Alocacao{}, err
 	}
+	defer tx.Rollback()
 
-	a.Professor = p
-	a.Sala = s
-	a.Turma = t
+	alocacao, err := createAlocacao(tx, a)
+	if err != nil {
+		return models.Alocacao{}, err
+	}
 
-	return a, nil
+	return alocacao, tx.Commit()
 }
 
-// Create cria uma nova alocação
-func (r *AlocacaoRepository) Create(a models.Alocacao) (models.Alocacao, error) {
-	// Verificar se a sala está disponível no horário solicitado
-	var count int
-	query := `
-		SELECT COUNT(*) FROM alocacoes 
-		WHERE sala_id = $1 AND dia_semana = $2 AND 
-		((horario_inicio <= $3 AND horario_fim > $3) OR 
-		(horario_inicio < $4 AND horario_fim >= $4) OR
-		(horario_inicio >= $3 AND horario_fim <= $4))
-	`
+// createAlocacao cria uma nova alocação dentro da transação informada
+func createAlocacao(db executor, a models.Alocacao) (models.Alocacao, error) {
+	if err := validarAlocacao(a); err != nil {
+		return models.Alocacao{}, err
+	}
 
-	err := r.DB.QueryRow(query, a.SalaID, a.DiaSemana, a.HorarioInicio, a.HorarioFim).Scan(&count)
+	// Sem período letivo informado, a alocação pertence ao período letivo atual
+	periodo, err := resolverPeriodoLetivo(db, a.PeriodoLetivoID)
 	if err != nil {
 		return models.Alocacao{}, err
 	}
+	a.PeriodoLetivoID = periodo.ID
 
-	if count > 0 {
-		return models.Alocacao{}, fmt.Errorf("sala já está alocada neste horário")
+	if err := validarRecorrencia(&a, periodo); err != nil {
+		return models.Alocacao{}, err
 	}
 
-	// Inserir a alocação
-	insertQuery := `
-		INSERT INTO alocacoes (professor_id, sala_id, turma_id, dia_semana, horario_inicio, horario_fim) 
-		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
-	`
+	// Bloquear a sala, o professor e a turma até o fim da transação
+	err = bloquearRecursos(db, a)
+	if err != nil {
+		return models.Alocacao{}, err
+	}
 
-	err = r.DB.QueryRow(insertQuery, a.ProfessorID, a.SalaID, a.TurmaID, a.DiaSemana, a.HorarioInicio, a.HorarioFim).Scan(&a.ID)
+	// Verificar se a sala, o professor e a turma estão disponíveis no horário solicitado
+	err = verificarConflitos(db, a)
 	if err != nil {
 		return models.Alocacao{}, err
 	}
 
-	// Buscar a alocação completa com os detalhes
-	return r.GetByID(a.ID)
-}
+	// Verificar as demais regras de alocação
+	err = validarRegras(db, a)
+	if err != nil {
+		return models.Alocacao{}, err
+	}
 
-// Update atualiza uma alocação existente
-func (r *AlocacaoRepository) Update(a models.Alocacao) error {
-	// Verificar se a sala está disponível no horário solicitado (excluindo a própria alocação)
-	var count int
-	query := `
-		SELECT COUNT(*) FROM alocacoes 
-		WHERE sala_id = $1 AND dia_semana = $2 AND 
-		((horario_inicio <= $3 AND horario_fim > $3) OR 
-		(horario_inicio < $4 AND horario_fim >= $4) OR
-		(horario_inicio >= $3 AND horario_fim <= $4)) AND
-		id != $5
+	// Inserir a alocação
+	insertQuery := `
+		INSERT INTO alocacoes (professor_id, sala_id, turma_id, dia_semana, horario_inicio, horario_fim, ignorar_capacidade, disciplina_id, periodo_letivo_id,
+		intervalo_semanas, data_inicio, data_fim, semanas) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8::INT, (SELECT disciplina_id FROM turmas WHERE id = $3)), $9, $10, $11, $12, $13) RETURNING id
 	`
 
-	err := r.DB.QueryRow(query, a.SalaID, a.DiaSemana, a.HorarioInicio, a.HorarioFim, a.ID).Scan(&count)
+	intervalo, recorrenciaInicio, recorrenciaFim, semanas := colunasRecorrencia(a.Recorrencia)
+	err = db.QueryRow(insertQuery, a.ProfessorID, a.SalaID, a.TurmaID, a.DiaSemana, a.HorarioInicio, a.HorarioFim, a.IgnorarCapacidade, a.DisciplinaID, a.PeriodoLetivoID,
+		intervalo, recorrenciaInicio, recorrenciaFim, semanas).Scan(&a.ID)
 	if err != nil {
-		return err
+		return models.Alocacao{}, traduzirErroSobreposicao(a, err)
 	}
 
-	if count > 0 {
-		return fmt.Errorf("sala já está alocada neste horário")
+	// Buscar a alocação completa com os detalhes e os avisos de deslocamento entre blocos
+	alocacao, err := getAlocacaoByID(db, a.ID)
+	if err != nil {
+		return models.Alocacao{}, err
 	}
 
-	// Atualizar a alocação
-	updateQuery := `
-		UPDATE alocacoes SET 
-		professor_id = $1, sala_id = $2, turma_id = $3, 
-		dia_semana = $4, horario_inicio = $5, horario_fim = $6 
-		WHERE id = $7
-	`
-
-	_, err = r.DB.Exec(updateQuery, a.ProfessorID, a.SalaID, a.TurmaID, a.DiaSemana, a.HorarioInicio, a.HorarioFim, a.ID)
-	return err
-}
-
-// Delete remove uma alocação pelo ID
-func (r *AlocacaoRepository) Delete(id int) error {
-	_, err := r.DB.Exec("DELETE FROM alocacoes WHERE id = $1", id)
-	return err
+	alocacao.Avisos, err = avisosDeslocamento(db, alocacao)
+	if err != nil {
+		return models.Alocacao{}, err
+	}
+	return alocacao, nil
 }
 
-// GetBySalaID retorna todas as alocações de uma sala específica
-func (r *AlocacaoRepository) GetBySalaID(salaID int) ([]models.Alocacao, error) {
-	query := `
-		SELECT 
-			a.id, a.professor_id, a.sala_id, a.turma_id, a.dia_semana, a.horario_inicio, a.horario_fim,
-			p.id, p.nome, p.email, p.formacao, p.disciplina,
-			s.id, s.numero, s.capacidade, s.bloco, s.tipo,
-			t.id, t.nome, t.curso, t.periodo, t.quant_alunos
-		FROM alocacoes a
-		JOIN professores p ON a.professor_id = p.id
-		JOIN salas s ON a.sala_id = s.id
-		JOIN turmas t ON a.turma_id = t.id
-		WHERE a.sala_id = $1
-	`
+// Update atualiza uma alocação existente, com a mesma garantia de atomicidade de Create, e retorna
+// os avisos de deslocamento entre blocos da alocação atualizada. Sem período letivo informado, a
+// alocação continua no período letivo em que já estava.
+func (r *AlocacaoRepository) Update(a models.Alocacao) ([]models.Violacao, error) {
+	if err := validarAlocacao(a); err != nil {
+		return nil, err
+	}
 
-	rows, err := r.DB.Query(query, salaID)
+	tx, err := r.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var alocacoes []models.Alocacao
-	for rows.Next() {
-		var a models.Alocacao
-		var p models.Professor
-		var s models.Sala
-		var t models.Turma
-
-		err := rows.Scan(
-			&a.ID, &a.ProfessorID, &a.SalaID, &a.TurmaID, &a.DiaSemana, &a.HorarioInicio, &a.HorarioFim,
-			&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina,
-			&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo,
-			&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos,
-		)
+	if a.PeriodoLetivoID == 0 {
+		err = tx.QueryRow("SELECT periodo_letivo_id FROM alocacoes WHERE id = $1", a.ID).Scan(&a.PeriodoLetivoID)
 		if err != nil {
 			return nil, err
 		}
-
-		a.Professor = p
-		a.Sala = s
-		a.Turma = t
-		alocacoes = append(alocacoes, a)
 	}
-
-	return alocacoes, nil
-}
-
-// GetByProfessorID retorna todas as alocações de um professor específico
-func (r *AlocacaoRepository) GetByProfessorID(professorID int) ([]models.Alocacao, error) {
-	query := `
-		SELECT 
-			a.id, a.professor_id, a.sala_id, a.turma_id, a.dia_semana, a.horario_inicio, a.horario_fim,
-			p.id, p.nome, p.email, p.formacao, p.disciplina,
-			s.id, s.numero, s.capacidade, s.bloco, s.tipo,
-			t.id, t.nome, t.curso, t.periodo, t.quant_alunos
-		FROM alocacoes a
-		JOIN professores p ON a.professor_id = p.id
-		JOIN salas s ON a.sala_id = s.id
-		JOIN turmas t ON a.turma_id = t.id
-		WHERE a.professor_id = $1
-	`
-
-	rows, err := r.DB.Query(query, professorID)
+	periodo, err := resolverPeriodoLetivo(tx, a.PeriodoLetivoID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var alocacoes []models.Alocacao
-	for rows.Next() {
-		var a models.Alocacao
-		var p models.Professor
-		var s models.Sala
-		var t models.Turma
-
-		err := rows.Scan(
-			&a.ID, &a.ProfessorID, &a.SalaID, &a.TurmaID, &a.DiaSemana, &a.HorarioInicio, &a.HorarioFim,
-			&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina,
-			&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo,
-			&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos,
-		)
-		if err != nil {
-			return nil, err
-		}
 
-		a.Professor = p
-		a.Sala = s
-		a.Turma = t
-		alocacoes = append(alocacoes, a)
+	if err := validarRecorrencia(&a, periodo); err != nil {
+		return nil, err
 	}
 
-	return alocacoes, nil
-}
-
-// GetByTurmaID retorna todas as alocações de uma turma específica
-func (r *AlocacaoRepository) GetByTurmaID(turmaID int) ([]models.Alocacao, error) {
-	query := `
-		SELECT 
-			a.id, a.professor_id, a.sala_id, a.turma_id, a.dia_semana, a.horario_inicio, a.horario_fim,
-			p.id, p.nome, p.email, p.formacao, p.disciplina,
-			s.id, s.numero, s.capacidade, s.bloco, s.tipo,
-			t.id, t.nome, t.curso, t.periodo, t.quant_alunos
-		FROM alocacoes a
-		JOIN professores p ON a.professor_id = p.id
-		JOIN salas s ON a.sala_id = s.id
-		JOIN turmas t ON a.turma_id = t.id
-		WHERE a.turma_id = $1
-	`
-
-	rows, err := r.DB.Query(query, turmaID)
+	// Bloquear a sala, o professor e a turma até o fim da transação
+	err = bloquearRecursos(tx, a)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var alocacoes []models.Alocacao
-	for rows.Next() {
-		var a models.Alocacao
-		var p models.Professor
-		var s models.Sala
-		var t models.Turma
-
-		err := rows.Scan(
-			&a.ID, &a.ProfessorID, &a.SalaID, &a.TurmaID, &a.DiaSemana, &a.HorarioInicio, &a.HorarioFim,
-			&p.ID, &p.Nome, &p.Email, &p.Formacao, &p.Disciplina,
-			&s.ID, &s.Numero, &s.Capacidade, &s.Bloco, &s.Tipo,
-			&t.ID, &t.Nome, &t.Curso, &t.Periodo, &t.QuantAlunos,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		a.Professor = p
-		a.Sala = s
-		a.Turma = t
-		alocacoes = append(alocacoes, a)
+	// Verificar se a sala, o professor e a turma estão disponíveis no horário solicitado (excluindo a própria alocação)
+	err = verificarConflitos(tx, a)
+	if err != nil {
+		return nil, err
 	}
 
-	return alocacoes, nil
-}
+	// Verificar as demais regras de alocação
+	err = validarRegras(tx, a)
+	if err != nil {
+		return nil, err
+	}
 
-// OrganizarAlocacoesAutomaticas organiza alocações automaticamente para um dia e horário específicos
-func (r *AlocacaoRepository) OrganizarAlocacoesAutomaticas(diaSemana, horarioInicio, horarioFim string) ([]models.Alocacao, error) {
-	// Inicializar o gerador de números aleatórios
-	rand.Seed(time.Now().UnixNano())
+	// Atualizar a alocação. Como ela já passou pelas regras, deixa de estar marcada em um bloqueio de sala
+	updateQuery := `
+		UPDATE alocacoes SET 
+		professor_id = $1, sala_id = $2, turma_id = $3, 
+		dia_semana = $4, horario_inicio = $5, horario_fim = $6, ignorar_capacidade = $7, 
+		disciplina_id = COALESCE($9::INT, (SELECT disciplina_id FROM turmas WHERE id = $3)), periodo_letivo_id = $10, bloqueio_sala_id = NULL,
+		intervalo_semanas = $11, data_inicio = $12, data_fim = $13, semanas = $14 
+		WHERE id = $8
+	`
 
-	// 1. Obter todos os professores disponíveis
-	professores, err := r.getProfessoresDisponiveis(diaSemana, horarioInicio, horarioFim)
+	intervalo, recorrenciaInicio, recorrenciaFim, semanas := colunasRecorrencia(a.Recorrencia)
+	_, err = tx.Exec(updateQuery, a.ProfessorID, a.SalaID, a.TurmaID, a.DiaSemana, a.HorarioInicio, a.HorarioFim, a.IgnorarCapacidade, a.ID, a.DisciplinaID, a.PeriodoLetivoID,
+		intervalo, recorrenciaInicio, recorrenciaFim, semanas)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter professores disponíveis: %v", err)
+		return nil, traduzirErroSobreposicao(a, err)
 	}
 
-	// 2. Obter todas as salas disponíveis
-	salas, err := r.getSalasDisponiveis(diaSemana, horarioInicio, horarioFim)
+	// Descartar as exceções de datas que deixaram de ser aulas da alocação com o novo dia, período letivo ou recorrência
+	err = descartarExcecoesForaDasAulas(tx, a, periodo)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter salas disponíveis: %v", err)
+		return nil, err
 	}
 
-	// 3. Obter todas as turmas disponíveis
-	turmas, err := r.getTurmasDisponiveis(diaSemana, horarioInicio, horarioFim)
+	sala, err := getSalaByID(tx, a.SalaID)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao obter turmas disponíveis: %v", err)
+		return nil, err
 	}
-
-	// 4. Verificar se há recursos suficientes para fazer alocações
-	if len(professores) == 0 || len(salas) == 0 || len(turmas) == 0 {
-		return nil, fmt.Errorf("não há recursos suficientes para fazer alocações")
+	_, avisos, err := analisarDeslocamentos(tx, a, sala)
+	if err != nil {
+		return nil, err
 	}
 
-	// 5. Criar alocações automaticamente
-	var alocacoesCriadas []models.Alocacao
-
-	// Usar o número mínimo entre professores, salas e turmas disponíveis
-	numAlocacoes := min(len(professores), min(len(salas), len(turmas)))
-
-	// Embaralhar os arrays de professores, salas e turmas para alocação aleatória
-	rand.Shuffle(len(professores), func(i, j int) {
-		professores[i], professores[j] = professores[j], professores[i]
-	})
-
-	rand.Shuffle(len(salas), func(i, j int) {
-		salas[i], salas[j] = salas[j], salas[i]
-	})
-
-	rand.Shuffle(len(turmas), func(i, j int) {
-		turmas[i], turmas[j] = turmas[j], turmas[i]
-	})
+	return avisos, tx.Commit()
+}
 
-	for i := 0; i < numAlocacoes; i++ {
-		// Criar uma nova alocação
-		novaAlocacao := models.Alocacao{
-			ProfessorID:   professores[i].ID,
-			SalaID:        salas[i].ID,
-			TurmaID:       turmas[i].ID,
-			DiaSemana:     diaSemana,
-			HorarioInicio: horarioInicio,
-			HorarioFim:    horarioFim,
-			Professor:     professores[i],
-			Sala:          salas[i],
-			Turma:         turmas[i],
-		}
+// Delete remove uma alocação pelo ID
+func (r *AlocacaoRepository) Delete(id int) error {
+	_, err := r.DB.Exec("DELETE FROM alocacoes WHERE id = $1", id)
+	return err
+}
 
-		// Inserir a alocação no banco de dados
-		alocacaoCriada, err := r.Create(novaAlocacao)
-		if err != nil {
-			// Continuar mesmo se houver erro em uma alocação específica
-			continue
-		}
+// GetBySalaID retorna todas as alocações de uma sala específica no período letivo informado (zero para o atual)
+func (r *AlocacaoRepository) GetBySalaID(salaID, periodoLetivoID int) ([]models.Alocacao, error) {
+	return queryAlocacoesDoPeriodo(r.DB, periodoLetivoID, " AND a.sala_id = $1", salaID)
+}
 
-		alocacoesCriadas = append(alocacoesCriadas, alocacaoCriada)
-	}
+// GetByProfessorID retorna todas as alocações de um professor específico no período letivo informado (zero para o atual)
+func (r *AlocacaoRepository) GetByProfessorID(professorID, periodoLetivoID int) ([]models.Alocacao, error) {
+	return queryAlocacoesDoPeriodo(r.DB, periodoLetivoID, " AND a.professor_id = $1", professorID)
+}
 
-	if len(alocacoesCriadas) == 0 {
-		return nil, fmt.Errorf("não foi possível criar nenhuma alocação")
-	}
+// GetByTurmaID retorna todas as alocações de uma turma específica no período letivo informado (zero para o atual)
+func (r *AlocacaoRepository) GetByTurmaID(turmaID, periodoLetivoID int) ([]models.Alocacao, error) {
+	return queryAlocacoesDoPeriodo(r.DB, periodoLetivoID, " AND a.turma_id = $1", turmaID)
+}
 
-	return alocacoesCriadas, nil
+// GetByDisciplinaID retorna todas as alocações de uma disciplina específica no período letivo informado (zero para o atual)
+func (r *AlocacaoRepository) GetByDisciplinaID(disciplinaID, periodoLetivoID int) ([]models.Alocacao, error) {
+	return queryAlocacoesDoPeriodo(r.DB, periodoLetivoID, " AND a.disciplina_id = $1", disciplinaID)
 }
 
-// getProfessoresDisponiveis retorna professores disponíveis em um determinado dia e horário
-func (r *AlocacaoRepository) getProfessoresDisponiveis(diaSemana, horarioInicio, horarioFim string) ([]models.Professor, error) {
+// getProfessoresDisponiveis retorna professores disponíveis em um determinado dia e horário do período letivo
+func (r *AlocacaoRepository) getProfessoresDisponiveis(periodo models.PeriodoLetivo, diaSemana models.DiaSemana, horarioInicio, horarioFim models.Horario) ([]models.Professor, error) {
 	// Obter todos os professores
 	professorRepo := &ProfessorRepository{DB: r.DB}
 	allProfessores, err := professorRepo.GetAll()
@@ -627,44 +680,47 @@ func (r *AlocacaoRepository) getProfessoresDisponiveis(diaSemana, horarioInicio,
 		return nil, err
 	}
 
-	// Consultar professores já alocados no horário especificado
-	query := `
-		SELECT DISTINCT professor_id FROM alocacoes 
-		WHERE dia_semana = $1 AND 
-		((horario_inicio <= $2 AND horario_fim > $2) OR 
-		(horario_inicio < $3 AND horario_fim >= $3) OR
-		(horario_inicio >= $2 AND horario_fim <= $3))
-	`
+	// Consultar professores já ocupados no horário especificado, como na verificação de conflitos
+	aula := models.Alocacao{DiaSemana: diaSemana, HorarioInicio: horarioInicio, HorarioFim: horarioFim, PeriodoLetivoID: periodo.ID}
+	ocupados, err := recursosOcupados(r.DB, aula, periodo)
+	if err != nil {
+		return nil, err
+	}
+	alocadosIDs := ocupados["professor"]
 
-	rows, err := r.DB.Query(query, diaSemana, horarioInicio, horarioFim)
+	// Janelas de disponibilidade e indisponibilidade declaradas pelos professores
+	janelas, err := disponibilidadesPorProfessor(r.DB)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	// Criar um mapa de IDs de professores já alocados
-	alocadosIDs := make(map[int]bool)
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
-		}
-		alocadosIDs[id] = true
+	// Aulas que cada professor já tem na semana, para respeitar os limites de carga horária
+	aulas, err := aulasPorProfessor(r.DB, periodo)
+	if err != nil {
+		return nil, err
 	}
+	nova := aulaProfessor{HorarioAula: models.HorarioAula{DiaSemana: diaSemana, HorarioInicio: horarioInicio, HorarioFim: horarioFim}}
 
 	// Filtrar professores disponíveis
 	var professoresDisponiveis []models.Professor
 	for _, p := range allProfessores {
-		if !alocadosIDs[p.ID] {
-			professoresDisponiveis = append(professoresDisponiveis, p)
+		if alocadosIDs[p.ID] {
+			continue
 		}
+		if motivoIndisponibilidade(janelas[p.ID], diaSemana, horarioInicio, horarioFim) != "" {
+			continue
+		}
+		if len(violacoesCargaNasSemanas(p, aulas[p.ID], nova, periodo)) > 0 {
+			continue
+		}
+		professoresDisponiveis = append(professoresDisponiveis, p)
 	}
 
 	return professoresDisponiveis, nil
 }
 
-// getSalasDisponiveis retorna salas disponíveis em um determinado dia e horário
-func (r *AlocacaoRepository) getSalasDisponiveis(diaSemana, horarioInicio, horarioFim string) ([]models.Sala, error) {
+// getSalasDisponiveis retorna salas disponíveis em um determinado dia e horário do período letivo
+func (r *AlocacaoRepository) getSalasDisponiveis(periodo models.PeriodoLetivo, diaSemana models.DiaSemana, horarioInicio, horarioFim models.Horario) ([]models.Sala, error) {
 	// Obter todas as salas
 	salaRepo := &SalaRepository{DB: r.DB}
 	allSalas, err := salaRepo.GetAll()
@@ -672,35 +728,25 @@ func (r *AlocacaoRepository) getSalasDisponiveis(diaSemana, horarioInicio, horar
 		return nil, err
 	}
 
-	// Consultar salas já alocadas no horário especificado
-	query := `
-		SELECT DISTINCT sala_id FROM alocacoes 
-		WHERE dia_semana = $1 AND 
-		((horario_inicio <= $2 AND horario_fim > $2) OR 
-		(horario_inicio < $3 AND horario_fim >= $3) OR
-		(horario_inicio >= $2 AND horario_fim <= $3))
-	`
-
-	rows, err := r.DB.Query(query, diaSemana, horarioInicio, horarioFim)
+	// Consultar salas já ocupadas no horário especificado, como na verificação de conflitos
+	aula := models.Alocacao{DiaSemana: diaSemana, HorarioInicio: horarioInicio, HorarioFim: horarioFim, PeriodoLetivoID: periodo.ID}
+	ocupados, err := recursosOcupados(r.DB, aula, periodo)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	alocadosIDs := ocupados["sala"]
 
-	// Criar um mapa de IDs de salas já alocadas
-	alocadosIDs := make(map[int]bool)
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
-		}
-		alocadosIDs[id] = true
+	// Bloqueios das salas, como reformas ou provas
+	bloqueios, err := bloqueiosPorSala(r.DB)
+	if err != nil {
+		return nil, err
 	}
 
 	// Filtrar salas disponíveis
 	var salasDisponiveis []models.Sala
 	for _, s := range allSalas {
-		if !alocadosIDs[s.ID] {
+		_, bloqueada := bloqueioQueAfeta(bloqueios[s.ID], aula, periodo)
+		if !alocadosIDs[s.ID] && !bloqueada {
 			salasDisponiveis = append(salasDisponiveis, s)
 		}
 	}
@@ -708,8 +754,8 @@ func (r *AlocacaoRepository) getSalasDisponiveis(diaSemana, horarioInicio, horar
 	return salasDisponiveis, nil
 }
 
-// getTurmasDisponiveis retorna turmas disponíveis em um determinado dia e horário
-func (r *AlocacaoRepository) getTurmasDisponiveis(diaSemana, horarioInicio, horarioFim string) ([]models.Turma, error) {
+// getTurmasDisponiveis retorna turmas disponíveis em um determinado dia e horário do período letivo
+func (r *AlocacaoRepository) getTurmasDisponiveis(periodo models.PeriodoLetivo, diaSemana models.DiaSemana, horarioInicio, horarioFim models.Horario) ([]models.Turma, error) {
 	// Obter todas as turmas
 	turmaRepo := &TurmaRepository{DB: r.DB}
 	allTurmas, err := turmaRepo.GetAll()
@@ -717,46 +763,31 @@ func (r *AlocacaoRepository) getTurmasDisponiveis(diaSemana, horarioInicio, hora
 		return nil, err
 	}
 
-	// Consultar turmas já alocadas no horário especificado
-	query := `
-		SELECT DISTINCT turma_id FROM alocacoes 
-		WHERE dia_semana = $1 AND 
-		((horario_inicio <= $2 AND horario_fim > $2) OR 
-		(horario_inicio < $3 AND horario_fim >= $3) OR
-		(horario_inicio >= $2 AND horario_fim <= $3))
-	`
-
-	rows, err := r.DB.Query(query, diaSemana, horarioInicio, horarioFim)
+	// Consultar turmas já ocupadas no horário especificado, como na verificação de conflitos
+	aula := models.Alocacao{DiaSemana: diaSemana, HorarioInicio: horarioInicio, HorarioFim: horarioFim, PeriodoLetivoID: periodo.ID}
+	ocupados, err := recursosOcupados(r.DB, aula, periodo)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	alocadosIDs := ocupados["turma"]
 
-	// Criar um mapa de IDs de turmas já alocadas
-	alocadosIDs := make(map[int]bool)
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
-		}
-		alocadosIDs[id] = true
+	// Turnos das turmas, para descartar as que não podem ter aula neste horário
+	turnos, err := turnosPorID(r.DB)
+	if err != nil {
+		return nil, err
 	}
 
 	// Filtrar turmas disponíveis
 	var turmasDisponiveis []models.Turma
 	for _, t := range allTurmas {
-		if !alocadosIDs[t.ID] {
-			turmasDisponiveis = append(turmasDisponiveis, t)
+		if alocadosIDs[t.ID] {
+			continue
+		}
+		if t.TurnoID != nil && !turnoComporta(turnos[*t.TurnoID], horarioInicio, horarioFim) {
+			continue
 		}
+		turmasDisponiveis = append(turmasDisponiveis, t)
 	}
 
 	return turmasDisponiveis, nil
 }
-
-// Função auxiliar para obter o mínimo entre dois inteiros
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
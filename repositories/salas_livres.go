@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// BuscarSalasLivres retorna as salas livres no dia e horário do filtro que atendem à capacidade
+// mínima, ao bloco e ao tipo pedidos, no período letivo informado ou no atual. As salas que melhor
+// se ajustam à capacidade pedida, com menos lugares ociosos, vêm primeiro.
+func (r *AlocacaoRepository) BuscarSalasLivres(filtro models.FiltroSalasLivres) ([]models.SalaLivre, error) {
+	if filtro.DiaSemana.Numero() == 0 {
+		return nil, fmt.Errorf("%w: dia_semana é obrigatório", ErrAlocacaoInvalida)
+	}
+	if err := models.ValidarIntervalo(filtro.HorarioInicio, filtro.HorarioFim); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAlocacaoInvalida, err)
+	}
+	if filtro.CapacidadeMinima < 0 {
+		return nil, fmt.Errorf("%w: capacidade_minima não pode ser negativa", ErrAlocacaoInvalida)
+	}
+
+	periodo, err := resolverPeriodoLetivo(r.DB, filtro.PeriodoLetivoID)
+	if err != nil {
+		return nil, err
+	}
+
+	salas, err := r.getSalasDisponiveis(periodo, filtro.DiaSemana, filtro.HorarioInicio, filtro.HorarioFim)
+	if err != nil {
+		return nil, err
+	}
+
+	livres := []models.SalaLivre{}
+	for _, s := range salas {
+		if s.Capacidade < filtro.CapacidadeMinima {
+			continue
+		}
+		if filtro.Bloco != "" && normalizarBloco(s.Bloco) != normalizarBloco(filtro.Bloco) {
+			continue
+		}
+		if filtro.Tipo != "" && normalizarTexto(s.Tipo) != normalizarTexto(filtro.Tipo) {
+			continue
+		}
+		livres = append(livres, models.SalaLivre{Sala: s, LugaresOciosos: s.Capacidade - filtro.CapacidadeMinima})
+	}
+
+	sort.SliceStable(livres, func(i, j int) bool {
+		if livres[i].LugaresOciosos != livres[j].LugaresOciosos {
+			return livres[i].LugaresOciosos < livres[j].LugaresOciosos
+		}
+		return livres[i].Numero < livres[j].Numero
+	})
+
+	return livres, nil
+}
+
+// normalizarBloco compara blocos informados como "B" ou "Bloco B"
+func normalizarBloco(bloco string) string {
+	return strings.TrimPrefix(normalizarTexto(bloco), "bloco ")
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/cristiantebaldi/class-organize-api/models"
+)
+
+// ErrTurnoInvalido indica que o turno informado é inválido
+var ErrTurnoInvalido = errors.New("dados do turno inválidos")
+
+// TurnoRepository gerencia operações de banco de dados para turnos
+type TurnoRepository struct {
+	DB *sql.DB
+}
+
+// NewTurnoRepository cria um novo repositório de turnos
+func NewTurnoRepository(db *sql.DB) *TurnoRepository {
+	return &TurnoRepository{DB: db}
+}
+
+// turnoSelectQuery é a consulta base de turnos
+const turnoSelectQuery = "SELECT id, nome, horario_inicio, horario_fim FROM turnos"
+
+// scanTurno lê uma linha da consulta base de turnos
+func scanTurno(row interface{ Scan(dest ...any) error }) (models.Turno, error) {
+	var t models.Turno
+	err := row.Scan(&t.ID, &t.Nome, &t.HorarioInicio, &t.HorarioFim)
+	if err != nil {
+		return models.Turno{}, err
+	}
+	return t, nil
+}
+
+// queryTurnos retorna todos os turnos usando a conexão ou transação informada
+func queryTurnos(db executor) ([]models.Turno, error) {
+	rows, err := db.Query(turnoSelectQuery + " ORDER BY horario_inicio, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	turnos := []models.Turno{}
+	for rows.Next() {
+		t, err := scanTurno(rows)
+		if err != nil {
+			return nil, err
+		}
+		turnos = append(turnos, t)
+	}
+
+	return turnos, rows.Err()
+}
+
+// GetAll retorna todos os turnos
+func (r *TurnoRepository) GetAll() ([]models.Turno, error) {
+	return queryTurnos(r.DB)
+}
+
+// GetByID retorna um turno pelo ID
+func (r *TurnoRepository) GetByID(id int) (models.Turno, error) {
+	return getTurnoByID(r.DB, id)
+}
+
+// getTurnoByID retorna um turno pelo ID usando a conexão ou transação informada
+func getTurnoByID(db executor, id int) (models.Turno, error) {
+	return scanTurno(db.QueryRow(turnoSelectQuery+" WHERE id = $1", id))
+}
+
+// Create cria um novo turno
+func (r *TurnoRepository) Create(t models.Turno) (models.Turno, error) {
+	if err := validarTurno(t); err != nil {
+		return models.Turno{}, err
+	}
+
+	query := `INSERT INTO turnos (nome, horario_inicio, horario_fim) VALUES ($1, $2, $3) RETURNING id`
+
+	err := r.DB.QueryRow(query, t.Nome, t.HorarioInicio, t.HorarioFim).Scan(&t.ID)
+	if err != nil {
+		return models.Turno{}, err
+	}
+
+	return t, nil
+}
+
+// Update atualiza um turno existente
+func (r *TurnoRepository) Update(t models.Turno) error {
+	if err := validarTurno(t); err != nil {
+		return err
+	}
+
+	query := `UPDATE turnos SET nome = $1, horario_inicio = $2, horario_fim = $3 WHERE id = $4`
+
+	_, err := r.DB.Exec(query, t.Nome, t.HorarioInicio, t.HorarioFim, t.ID)
+	return err
+}
+
+// Delete remove um turno pelo ID
+func (r *TurnoRepository) Delete(id int) error {
+	_, err := r.DB.Exec("DELETE FROM turnos WHERE id = $1", id)
+	return err
+}
+
+// validarTurno verifica se o turno tem nome e uma janela de horário válida
+func validarTurno(t models.Turno) error {
+	if t.Nome == "" {
+		return fmt.Errorf("%w: nome é obrigatório", ErrTurnoInvalido)
+	}
+	if err := models.ValidarIntervalo(t.HorarioInicio, t.HorarioFim); err != nil {
+		return fmt.Errorf("%w: %v", ErrTurnoInvalido, err)
+	}
+	return nil
+}
+
+// turnoComporta verifica se a aula cabe inteira na janela de horário do turno
+func turnoComporta(t models.Turno, inicio, fim models.Horario) bool {
+	return t.HorarioInicio <= inicio && fim <= t.HorarioFim
+}
+
+// turnosPorID retorna todos os turnos indexados pelo ID
+func turnosPorID(db executor) (map[int]models.Turno, error) {
+	turnos, err := queryTurnos(db)
+	if err != nil {
+		return nil, err
+	}
+
+	porID := make(map[int]models.Turno, len(turnos))
+	for _, t := range turnos {
+		porID[t.ID] = t
+	}
+	return porID, nil
+}